@@ -16,6 +16,11 @@ type PixelIO struct {
 	window      *pixelgl.Window
 	spritesheet *spritesheet
 	batch       *pixel.Batch
+
+	// The game's appearance, kept up to date by the game's events, and
+	// whether it's changed since it was last drawn
+	appearance map[ms.Pos]ms.TileType
+	changed    bool
 }
 
 func New() *PixelIO {
@@ -57,8 +62,10 @@ func (io *PixelIO) Run() {
 		io.batch = pixel.NewBatch(
 			&pixel.TrianglesData{}, io.spritesheet.SheetPicture)
 
-		// Draw to the batch
-		io.drawToBatch(io.game.Appearance(0, 0, w, h))
+		// Draw to the batch, and update the appearance from the game's events
+		io.appearance = io.game.Appearance(0, 0, w, h)
+		io.drawToBatch(io.appearance)
+		io.game.Subscribe(io.handleEvent)
 
 		// Start the main loop
 		for !io.window.Closed() {
@@ -70,8 +77,6 @@ func (io *PixelIO) Run() {
 				y := int(mousePos.Y / tileDrawSize)
 				// Uncover the tile
 				io.game.Uncover(x, y)
-				// Redraw to the batch
-				io.drawToBatch(io.game.Appearance(0, 0, w, h))
 			}
 			if io.window.JustPressed(pixelgl.MouseButtonRight) {
 				// Get the tile's position from the mouse
@@ -80,8 +85,12 @@ func (io *PixelIO) Run() {
 				y := int(mousePos.Y / tileDrawSize)
 				// Uncover the tile
 				io.game.Flag(x, y)
-				// Redraw to the batch
-				io.drawToBatch(io.game.Appearance(0, 0, w, h))
+			}
+
+			// Redraw to the batch if the appearance has changed
+			if io.changed {
+				io.drawToBatch(io.appearance)
+				io.changed = false
 			}
 
 			// Draw the mines
@@ -90,3 +99,26 @@ func (io *PixelIO) Run() {
 		}
 	})
 }
+
+// handleEvent updates the appearance from one of the game's events
+func (io *PixelIO) handleEvent(e ms.Event) {
+	switch e := e.(type) {
+	case ms.TilesRevealedEvent:
+		for pos, tileType := range e.Tiles {
+			io.appearance[pos] = tileType
+		}
+	case ms.TilesHiddenEvent:
+		for pos, tileType := range e.Tiles {
+			io.appearance[pos] = tileType
+		}
+	case ms.FlagToggledEvent:
+		if e.Flagged {
+			io.appearance[e.Pos] = ms.TileTypeFlag
+		} else {
+			io.appearance[e.Pos] = ms.TileTypeHidden
+		}
+	default:
+		return
+	}
+	io.changed = true
+}
@@ -42,11 +42,13 @@ func newSpritesheet() (s *spritesheet, err error) {
 	// Create the sprites
 	s.Sprites = make(map[ms.TileType]*pixel.Sprite)
 	x := float64(0)
-	for tileType := ms.TileTypeEmpty; tileType < ms.NumTileTypes; tileType++ {
+	for tileType := ms.TileTypeEmpty; tileType <= ms.TileTypeFlag; tileType++ {
 		s.Sprites[tileType] = pixel.NewSprite(s.SheetPicture,
 			pixel.R(x, 0, x+spriteSize, spriteSize))
 		x += spriteSize
 	}
+	// The spritesheet doesn't have an exploded mine yet
+	s.Sprites[ms.TileTypeExploded] = s.Sprites[ms.TileTypeMine]
 
 	return s, err
 }
@@ -55,6 +55,12 @@ func (io *WebIO) eventHandler(_ js.Value, args []js.Value) interface{} {
 		io.handleUncover(msg)
 	case "flag":
 		io.handleFlag(msg)
+	case "chord":
+		io.handleChord(msg)
+	case "undo":
+		io.handleUndo(msg)
+	case "redo":
+		io.handleRedo(msg)
 	case "save":
 		io.handleSave(msg)
 	case "load":
@@ -68,13 +74,44 @@ func (io *WebIO) eventHandler(_ js.Value, args []js.Value) interface{} {
 func (io *WebIO) handleInit(msg Message) {
 	consoleLog("Received '" + msg.Cmd + "'")
 	var g ms.Game
+	var opts []ms.Option
+	// If a number of lives was given, the game is only lost once they've all
+	// been lost (where Infinity means it's never lost)
+	if lives := msg.Data.Get("lives"); !lives.IsUndefined() {
+		if math.IsInf(lives.Float(), 1) {
+			opts = append(opts, ms.WithLives(ms.UnlimitedLives))
+		} else {
+			opts = append(opts, ms.WithLives(lives.Int()))
+		}
+	}
+	// If a first move policy was given, it decides which tiles are kept free
+	// of mines for the first move
+	if firstMove := msg.Data.Get("firstMove"); !firstMove.IsUndefined() {
+		opt, err := firstMoveOption(firstMove.String(),
+			msg.Data.Get("minOpening"))
+		if err != nil {
+			consoleLog("Error:", err)
+			sendError(msg, err)
+			return
+		}
+		opts = append(opts, opt)
+	}
 	// If a mine density was given, the minesweeper field is infinite
 	if !msg.Data.Get("mineDensity").IsUndefined() {
 		mineDensity := msg.Data.Get("mineDensity").Int()
+		// If a time limit (in milliseconds) or target score was given, the
+		// game ends when it's reached
+		if timeLimit := msg.Data.Get("timeLimit"); !timeLimit.IsUndefined() {
+			opts = append(opts, ms.WithTimeLimit(
+				time.Duration(timeLimit.Int())*time.Millisecond))
+		}
+		if targetScore := msg.Data.Get("targetScore"); !targetScore.IsUndefined() {
+			opts = append(opts, ms.WithTargetScore(targetScore.Int()))
+		}
 		consoleLogF("Creating infinite game (mineDensity = %d)", mineDensity)
 		// Create a new game with the options
 		var err error
-		g, err = ms.NewInfiniteGame(mineDensity)
+		g, err = ms.NewInfiniteGame(mineDensity, opts...)
 		if err != nil {
 			consoleLog("Error:", err)
 			sendError(msg, err)
@@ -86,11 +123,39 @@ func (io *WebIO) handleInit(msg Message) {
 		width := msg.Data.Get("width").Int()
 		height := msg.Data.Get("height").Int()
 		mines := msg.Data.Get("mines").Int()
-		consoleLogF("Creating game (width = %d, height = %d, mines = %d)",
-			width, height, mines)
+		// If a no-guess field was requested, give up looking for one after a
+		// second so the first move doesn't hang
+		noGuess := msg.Data.Get("noGuess").Truthy()
+		if noGuess {
+			opts = append(opts, ms.WithNoGuess(0, time.Second))
+		}
+		// If a toroidal field was requested, make the edges wrap around
+		toroidal := msg.Data.Get("toroidal").Truthy()
+		if toroidal {
+			opts = append(opts, ms.WithToroidal())
+		}
+		topology := "square"
+		if !msg.Data.Get("topology").IsUndefined() {
+			topology = msg.Data.Get("topology").String()
+		}
+		consoleLogF("Creating game (width = %d, height = %d, mines = %d, noGuess = %t, topology = %s, toroidal = %t)",
+			width, height, mines, noGuess, topology, toroidal)
 		// Create a new game with the options
 		var err error
-		g, err = ms.NewGame(width, height, mines)
+		switch topology {
+		case "square":
+			g, err = ms.NewGame(width, height, mines, opts...)
+		case "hex":
+			g, err = ms.NewHexGame(width, height, mines, opts...)
+		case "cube":
+			// The layers of a 3D game are stacked, so the appearance is
+			// depth times taller than height
+			depth := msg.Data.Get("depth").Int()
+			consoleLogF("Creating 3D game (depth = %d)", depth)
+			g, err = ms.NewGame3D(width, height, depth, mines, opts...)
+		default:
+			err = fmt.Errorf("unknown topology %s", topology)
+		}
 		if err != nil {
 			consoleLog("Error:", err)
 			sendError(msg, err)
@@ -98,9 +163,28 @@ func (io *WebIO) handleInit(msg Message) {
 		}
 	}
 
-	// If all goes well, set the game to the new one
+	// If all goes well, set the game to the new one, and send its settings
+	// back (including the defaults)
 	io.game = g
-	sendSuccess(msg)
+	sendSuccessWithPayload(msg, loadPayload(g))
+}
+
+// firstMoveOption returns the option for the first move policy with the given
+// name, where the "min opening" policy needs the number of tiles to open
+func firstMoveOption(name string, minOpening js.Value) (ms.Option, error) {
+	for _, policy := range []ms.FirstMovePolicy{ms.FirstMoveSafeOpening,
+		ms.FirstMoveUnprotected, ms.FirstMoveSafeTile} {
+		if name == policy.String() {
+			return ms.WithFirstMovePolicy(policy), nil
+		}
+	}
+	if name == ms.FirstMoveMinOpening.String() {
+		if minOpening.IsUndefined() {
+			return nil, fmt.Errorf("the %s policy needs a minOpening", name)
+		}
+		return ms.WithMinOpening(minOpening.Int()), nil
+	}
+	return nil, fmt.Errorf("unknown first move policy %s", name)
 }
 
 func appearancePayload(appearance map[ms.Pos]ms.TileType) interface{} {
@@ -121,9 +205,13 @@ func appearancePayload(appearance map[ms.Pos]ms.TileType) interface{} {
 			tileStr = "HIDDEN"
 		case ms.TileTypeMine:
 			tileStr = "MINE"
-		case ms.TileType1, ms.TileType2, ms.TileType3, ms.TileType4,
-			ms.TileType5, ms.TileType6, ms.TileType7, ms.TileType8:
-			tileStr = strconv.Itoa(int(tileType) - int(ms.TileType1) + 1)
+		case ms.TileTypeExploded:
+			tileStr = "EXPLODED"
+		default:
+			// Numbers can be above 8 with bigger neighbourhoods
+			if number, ok := tileType.Number(); ok {
+				tileStr = strconv.Itoa(number)
+			}
 		}
 
 		// Get the row from the payload
@@ -140,11 +228,24 @@ func appearancePayload(appearance map[ms.Pos]ms.TileType) interface{} {
 	return payload
 }
 
-func statePayload(s ms.GameState, timer time.Duration) map[string]interface{} {
-	return map[string]interface{}{
-		"state": s.String(),
-		"timer": timer.Milliseconds(),
+func statePayload(game ms.Game) map[string]interface{} {
+	var lives interface{}
+	if game.Lives() == ms.UnlimitedLives {
+		lives = js.Global().Get("Infinity")
+	} else {
+		lives = game.Lives()
+	}
+
+	payload := map[string]interface{}{
+		"state": game.State().String(),
+		"timer": game.SinceStart().Milliseconds(),
+		"lives": lives,
 	}
+	// Only infinite games are scored
+	if g, ok := game.(*ms.InfiniteGame); ok {
+		payload["score"] = g.Score()
+	}
+	return payload
 }
 
 func flagPayload(remaining float64) map[string]interface{} {
@@ -161,7 +262,7 @@ func flagPayload(remaining float64) map[string]interface{} {
 }
 
 func fullStatePayload(game ms.Game) map[string]interface{} {
-	payload := statePayload(game.State(), game.SinceStart())
+	payload := statePayload(game)
 	for key, value := range flagPayload(game.RemainingMines()) {
 		payload[key] = value
 	}
@@ -169,21 +270,52 @@ func fullStatePayload(game ms.Game) map[string]interface{} {
 }
 
 func loadPayload(game ms.Game) map[string]interface{} {
+	var payload map[string]interface{}
 	switch g := game.(type) {
 	case *ms.FiniteGame:
 		w, h := g.Size()
-		return map[string]interface{}{
-			"width":  w,
-			"height": h,
-			"mines":  g.StartingMines(),
+		payload = map[string]interface{}{
+			"width":    w,
+			"height":   h,
+			"mines":    g.StartingMines(),
+			"topology": "square",
+			"toroidal": g.Toroidal(),
+		}
+	case *ms.HexGame:
+		w, h := g.Size()
+		payload = map[string]interface{}{
+			"width":    w,
+			"height":   h,
+			"mines":    g.StartingMines(),
+			"topology": "hex",
+			"toroidal": g.Toroidal(),
+		}
+	case *ms.Game3D:
+		w, h, d := g.Size3D()
+		payload = map[string]interface{}{
+			"width":    w,
+			"height":   h,
+			"depth":    d,
+			"mines":    g.StartingMines(),
+			"topology": "cube",
+			"toroidal": g.Toroidal(),
 		}
 	case *ms.InfiniteGame:
-		return map[string]interface{}{
+		payload = map[string]interface{}{
 			"mineDensity": g.MineDensity(),
+			"timeLimit":   g.TimeLimit().Milliseconds(),
+			"targetScore": g.TargetScore(),
 		}
 	default:
 		panic("unknown game type")
 	}
+
+	policy, minOpening := game.FirstMovePolicy()
+	payload["firstMove"] = policy.String()
+	if policy == ms.FirstMoveMinOpening {
+		payload["minOpening"] = minOpening
+	}
+	return payload
 }
 
 func (io *WebIO) handleAppearance(msg Message) {
@@ -198,7 +330,12 @@ func (io *WebIO) handleState(msg Message) {
 
 func (io *WebIO) handleUncover(msg Message) {
 	io.game.Uncover(msg.Data.Get("x").Int(), msg.Data.Get("y").Int())
-	sendSuccessWithPayload(msg, statePayload(io.game.State(), io.game.SinceStart()))
+	sendSuccessWithPayload(msg, statePayload(io.game))
+}
+
+func (io *WebIO) handleChord(msg Message) {
+	io.game.Chord(msg.Data.Get("x").Int(), msg.Data.Get("y").Int())
+	sendSuccessWithPayload(msg, statePayload(io.game))
 }
 
 func (io *WebIO) handleFlag(msg Message) {
@@ -206,17 +343,34 @@ func (io *WebIO) handleFlag(msg Message) {
 	sendSuccessWithPayload(msg, flagPayload(remaining))
 }
 
+func historyPayload(game ms.Game, changed bool) map[string]interface{} {
+	payload := fullStatePayload(game)
+	payload["changed"] = changed
+	return payload
+}
+
+func (io *WebIO) handleUndo(msg Message) {
+	sendSuccessWithPayload(msg, historyPayload(io.game, io.game.Undo()))
+}
+
+func (io *WebIO) handleRedo(msg Message) {
+	sendSuccessWithPayload(msg, historyPayload(io.game, io.game.Redo()))
+}
+
 func (io *WebIO) handleSave(msg Message) {
 	consoleLog("Received '" + msg.Cmd + "'")
-	// todo clone the game to save multithreaded?
-	// Save the game state to the byte buffer
-	var buf bytes.Buffer
-	err := io.game.Save(&buf)
-	if err != nil {
-		sendError(msg, err)
-	} else {
-		sendSuccessWithPayload(msg, base64.StdEncoding.EncodeToString(buf.Bytes()))
-	}
+	// Save a copy of the game, so moves can be handled while it's saved
+	snapshot := io.game.Clone()
+	go func() {
+		// Save the game state to the byte buffer
+		var buf bytes.Buffer
+		err := snapshot.Save(&buf)
+		if err != nil {
+			sendError(msg, err)
+		} else {
+			sendSuccessWithPayload(msg, base64.StdEncoding.EncodeToString(buf.Bytes()))
+		}
+	}()
 }
 
 func (io *WebIO) handleLoad(msg Message) {
@@ -0,0 +1,70 @@
+package minesweeper
+
+import "math/bits"
+
+// The number of tiles in a chunk, and the number of words in a bitboard
+const (
+	chunkTiles    = ChunkSize * ChunkSize
+	bitboardWords = chunkTiles / 64
+)
+
+// bitboard has a bit for every tile in a chunk, where the tile at x, y is bit
+// y*ChunkSize + x
+type bitboard [bitboardWords]uint64
+
+// tileIndex returns the index of the bit for the given position in a chunk
+func tileIndex(p Pos) int {
+	return p.Y*ChunkSize + p.X
+}
+
+func (b *bitboard) get(i int) bool {
+	return b[i/64]&(1<<(i%64)) != 0
+}
+
+func (b *bitboard) set(i int, v bool) {
+	if v {
+		b[i/64] |= 1 << (i % 64)
+	} else {
+		b[i/64] &^= 1 << (i % 64)
+	}
+}
+
+// count returns the number of bits that are set
+func (b bitboard) count() int {
+	n := 0
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// any returns whether any bits are set
+func (b bitboard) any() bool {
+	for _, w := range b {
+		if w != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b bitboard) and(o bitboard) bitboard {
+	for i := range b {
+		b[i] &= o[i]
+	}
+	return b
+}
+
+func (b bitboard) or(o bitboard) bitboard {
+	for i := range b {
+		b[i] |= o[i]
+	}
+	return b
+}
+
+func (b bitboard) not() bitboard {
+	for i := range b {
+		b[i] = ^b[i]
+	}
+	return b
+}
@@ -0,0 +1,32 @@
+package minesweeper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBitboard(t *testing.T) {
+	a := assert.New(t)
+
+	var b bitboard
+	a.False(b.any())
+	for _, i := range []int{0, 63, 64, chunkTiles - 1} {
+		b.set(i, true)
+		a.True(b.get(i))
+	}
+	a.True(b.any())
+	a.Equal(4, b.count())
+	a.False(b.get(1))
+
+	b.set(63, false)
+	a.False(b.get(63))
+	a.Equal(3, b.count())
+
+	// The operations return new bitboards
+	a.Equal(chunkTiles-3, b.not().count())
+	a.Equal(0, b.and(b.not()).count())
+	a.Equal(chunkTiles, b.or(b.not()).count())
+	a.Equal(3, b.count())
+
+	a.Equal(tileIndex(Pos{3, 2}), 2*ChunkSize+3)
+}
@@ -0,0 +1,526 @@
+package minesweeper
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ChunkStore stores the chunks of an infinite game that don't fit in memory,
+// see WithChunkCache. Only the player's state in a chunk is stored, as the
+// mines are generated from the game's seed. The game only loads, ranges over
+// and deletes the chunks it stored itself, so it leaves any other chunks in
+// the store alone
+type ChunkStore interface {
+	// Load returns the state of the chunk at the given index, or nil if it
+	// isn't stored. Load (and Range) must be safe for concurrent use, as the
+	// game's read-only methods load chunks without changing the game, such as
+	// when several goroutines read a SyncGame at once. Store and Delete are
+	// only called while the game (or a clone sharing the store) is being
+	// changed
+	Load(chunkIndex Pos) ([]byte, error)
+
+	// Store stores the state of the chunk at the given index, replacing the
+	// chunk's state if it's already stored
+	Store(chunkIndex Pos, state []byte) error
+
+	// Delete removes the chunk at the given index, if it's stored
+	Delete(chunkIndex Pos) error
+
+	// Range calls f with every stored chunk, stopping at the first error
+	Range(f func(chunkIndex Pos, state []byte) error) error
+}
+
+// WithChunkCache keeps at most maxChunks of an infinite game's chunks in
+// memory between moves. The least recently used chunks are spilled to the
+// given store, and loaded again when they're needed. The chunks the player
+// hasn't touched aren't stored, as they can be generated again. The game can
+// use more chunks during a move, such as when a large area is uncovered.
+// Clones of the game share the store, and only keep a chunk in memory once
+// one of the games replaces it
+func WithChunkCache(maxChunks int, store ChunkStore) Option {
+	return func(o *options) {
+		o.maxChunks = maxChunks
+		o.chunkStore = store
+	}
+}
+
+// chunkCache limits the number of an infinite game's chunks in memory, see
+// WithChunkCache
+type chunkCache struct {
+	// The most chunks kept in memory, or 0 for no limit
+	maxChunks int
+	store     *sharedChunkStore
+
+	// The indexes of the chunks in memory, from the most recently used
+	recent   *list.List
+	elements map[Pos]*list.Element
+
+	// The chunks the game has put in the store
+	stored map[Pos]*sharedChunk
+
+	// The first error from the store
+	err error
+}
+
+// fail records the given error, if it's the first one
+func (c *chunkCache) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// sharedChunkStore is the store of a game's chunk cache, which is shared
+// with the game's clones so cloning doesn't copy the stored chunks. Each game
+// can replace or remove its chunks without changing the others: if another
+// game still has the chunk, the state in the store is moved into memory for
+// it first (copy on write)
+type sharedChunkStore struct {
+	mu    sync.Mutex
+	store ChunkStore
+
+	// The chunks in the store
+	chunks map[Pos]*sharedChunk
+}
+
+func newSharedChunkStore(store ChunkStore) *sharedChunkStore {
+	return &sharedChunkStore{
+		store:  store,
+		chunks: make(map[Pos]*sharedChunk),
+	}
+}
+
+// sharedChunk is a chunk's stored state, which one or more games have
+type sharedChunk struct {
+	// The number of games with the chunk
+	refs int
+
+	// The state, if it's been moved out of the store
+	state []byte
+}
+
+// share returns a copy of the given chunks, for a clone of the game
+func (s *sharedChunkStore) share(stored map[Pos]*sharedChunk) map[Pos]*sharedChunk {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := make(map[Pos]*sharedChunk, len(stored))
+	for chunkIndex, chunk := range stored {
+		chunk.refs++
+		c[chunkIndex] = chunk
+	}
+	return c
+}
+
+// load returns the state of the game's chunk at the given index, or nil if
+// the game hasn't stored it
+func (c *chunkCache) load(chunkIndex Pos) ([]byte, error) {
+	chunk := c.stored[chunkIndex]
+	if chunk == nil {
+		return nil, nil
+	}
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	if chunk.state != nil {
+		return chunk.state, nil
+	}
+	return c.store.store.Load(chunkIndex)
+}
+
+// spill stores the state of the chunk at the given index, which the game
+// has in memory
+func (c *chunkCache) spill(chunkIndex Pos, state []byte) error {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The game's previous state is replaced, but other games might still
+	// have it
+	own := c.stored[chunkIndex]
+	previous := s.chunks[chunkIndex]
+	if previous != nil && (previous != own || previous.refs > 1) {
+		old, err := s.store.Load(chunkIndex)
+		if err != nil {
+			return err
+		}
+		if old == nil {
+			return fmt.Errorf("chunk %v is missing from the store", chunkIndex)
+		}
+		previous.state = append([]byte(nil), old...)
+	}
+
+	err := s.store.Store(chunkIndex, state)
+	if err != nil {
+		return err
+	}
+	if own != nil {
+		own.refs--
+	}
+	chunk := &sharedChunk{refs: 1}
+	s.chunks[chunkIndex] = chunk
+	c.stored[chunkIndex] = chunk
+	return nil
+}
+
+// release removes the game's chunk at the given index, which is deleted
+// from the store if no other game has it
+func (c *chunkCache) release(chunkIndex Pos) error {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chunk := c.stored[chunkIndex]
+	delete(c.stored, chunkIndex)
+	chunk.refs--
+	if chunk.state != nil || chunk.refs > 0 {
+		return nil
+	}
+	delete(s.chunks, chunkIndex)
+	return s.store.Delete(chunkIndex)
+}
+
+// detach removes the game's chunk at the given index, with the given state,
+// from the store. Other games with the chunk keep the state in memory
+func (c *chunkCache) detach(chunkIndex Pos, state []byte) error {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chunk := c.stored[chunkIndex]
+	delete(c.stored, chunkIndex)
+	chunk.refs--
+	if chunk.state != nil {
+		return nil
+	}
+	if chunk.refs > 0 {
+		chunk.state = state
+	}
+	delete(s.chunks, chunkIndex)
+	return s.store.Delete(chunkIndex)
+}
+
+// rangeStored calls f with every chunk the game has put in the store
+func (c *chunkCache) rangeStored(f func(chunkIndex Pos, state []byte) error) error {
+	s := c.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for chunkIndex, chunk := range c.stored {
+		if chunk.state != nil {
+			err := f(chunkIndex, chunk.state)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return s.store.Range(func(chunkIndex Pos, state []byte) error {
+		chunk := c.stored[chunkIndex]
+		if chunk == nil || chunk.state != nil {
+			return nil
+		}
+		return f(chunkIndex, state)
+	})
+}
+
+// SetChunkCache changes the game's chunk cache, see WithChunkCache. A
+// maxChunks of 0 removes the limit, loading the stored chunks back into
+// memory. Any chunks in the previous store are moved to the new one
+func (g *InfiniteGame) SetChunkCache(maxChunks int, store ChunkStore) error {
+	if maxChunks < 0 {
+		return fmt.Errorf("invalid maximum number of chunks %d", maxChunks)
+	}
+	if maxChunks > 0 && store == nil {
+		return fmt.Errorf("a chunk cache needs a store")
+	}
+
+	cache := chunkCache{maxChunks: maxChunks, err: g.cache.err}
+	if maxChunks > 0 {
+		cache.store = newSharedChunkStore(store)
+		cache.stored = make(map[Pos]*sharedChunk)
+	}
+
+	// Move the stored chunks, either into memory or into the new store. They're
+	// all removed from the previous store first, in case it's the same store
+	// (any clones sharing it keep them in memory)
+	if g.cache.store != nil {
+		moved := newMemoryChunkStore()
+		err := g.cache.rangeStored(moved.Store)
+		if err != nil {
+			return err
+		}
+		for chunkIndex, state := range moved {
+			err = g.cache.detach(chunkIndex, state)
+			if err != nil {
+				return err
+			}
+		}
+		for chunkIndex, state := range moved {
+			if maxChunks == 0 {
+				chunk := g.newChunk(chunkIndex)
+				chunk.setStateFromBytes(state)
+				g.field[chunkIndex] = chunk
+				continue
+			}
+			err = cache.spill(chunkIndex, state)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	g.cache = cache
+	if maxChunks > 0 {
+		g.cache.recent = list.New()
+		g.cache.elements = make(map[Pos]*list.Element, len(g.field))
+		for chunkIndex := range g.field {
+			g.cache.elements[chunkIndex] = g.cache.recent.PushBack(chunkIndex)
+		}
+	}
+	g.trimChunks()
+	return g.cache.err
+}
+
+// ChunkStoreErr returns the first error from the game's chunk store, see
+// WithChunkCache. If there's been an error, the game might have lost the
+// player's state in a chunk, so it can't be saved
+func (g *InfiniteGame) ChunkStoreErr() error {
+	return g.cache.err
+}
+
+// storedChunk moves the chunk at the given index from the store into memory,
+// or returns nil if it isn't stored
+func (g *InfiniteGame) storedChunk(chunkIndex Pos) *chunk {
+	if g.cache.stored[chunkIndex] == nil {
+		return nil
+	}
+	state, err := g.cache.load(chunkIndex)
+	if err != nil {
+		g.cache.fail(err)
+	}
+	if state == nil {
+		return nil
+	}
+
+	chunk := g.newChunk(chunkIndex)
+	chunk.setStateFromBytes(state)
+	// The chunk is in memory now, so the stored state would go stale
+	err = g.cache.release(chunkIndex)
+	if err != nil {
+		g.cache.fail(err)
+	}
+	g.field[chunkIndex] = chunk
+	g.usedChunk(chunkIndex)
+	return chunk
+}
+
+// existingChunk returns the chunk at the given index if it's in memory or in
+// the store, or nil if it doesn't exist
+func (g *InfiniteGame) existingChunk(chunkIndex Pos) *chunk {
+	if chunk, ok := g.field[chunkIndex]; ok {
+		return chunk
+	}
+	return g.storedChunk(chunkIndex)
+}
+
+// usedChunk marks the chunk at the given index as the most recently used
+func (g *InfiniteGame) usedChunk(chunkIndex Pos) {
+	if g.cache.maxChunks == 0 {
+		return
+	}
+	if e, ok := g.cache.elements[chunkIndex]; ok {
+		g.cache.recent.MoveToFront(e)
+	} else {
+		g.cache.elements[chunkIndex] = g.cache.recent.PushFront(chunkIndex)
+	}
+}
+
+// trimChunks spills the least recently used chunks until there are at most
+// the cache's maximum in memory. Nothing is spilled during a move, as the
+// move can still be changing the chunks' tiles
+func (g *InfiniteGame) trimChunks() {
+	if g.cache.maxChunks == 0 || g.history.depth > 0 {
+		return
+	}
+	for len(g.field) > g.cache.maxChunks {
+		e := g.cache.recent.Back()
+		chunkIndex := e.Value.(Pos)
+		chunk := g.field[chunkIndex]
+		if chunk.touched() {
+			err := g.cache.spill(chunkIndex, chunk.stateToBytes())
+			if err != nil {
+				// Keep the chunk in memory, so it isn't lost
+				g.cache.fail(err)
+				return
+			}
+		}
+		delete(g.field, chunkIndex)
+		g.cache.recent.Remove(e)
+		delete(g.cache.elements, chunkIndex)
+	}
+}
+
+// clearChunks removes every chunk, from memory and the store. Only the chunks
+// the game stored (and no clones still have) are deleted from the store
+func (g *InfiniteGame) clearChunks() {
+	g.field = make(map[Pos]*chunk)
+	if g.cache.maxChunks == 0 {
+		return
+	}
+	g.cache.recent.Init()
+	g.cache.elements = make(map[Pos]*list.Element)
+
+	for chunkIndex := range g.cache.stored {
+		err := g.cache.release(chunkIndex)
+		if err != nil {
+			g.cache.fail(err)
+		}
+	}
+}
+
+// rangeChunks calls f with every chunk, in memory or in the store
+func (g *InfiniteGame) rangeChunks(f func(chunkIndex Pos, chunk *chunk) error) error {
+	for chunkIndex, chunk := range g.field {
+		err := f(chunkIndex, chunk)
+		if err != nil {
+			return err
+		}
+	}
+	if g.cache.store == nil {
+		return nil
+	}
+	return g.cache.rangeStored(func(chunkIndex Pos, state []byte) error {
+		chunk := g.newChunk(chunkIndex)
+		chunk.setStateFromBytes(state)
+		return f(chunkIndex, chunk)
+	})
+}
+
+// cloneCache returns a copy of the game's chunk cache. The clone shares the
+// stored chunks, which stay in the store (see sharedChunkStore)
+func (g *InfiniteGame) cloneCache() chunkCache {
+	c := chunkCache{maxChunks: g.cache.maxChunks, err: g.cache.err}
+	if c.maxChunks == 0 {
+		return c
+	}
+	c.store = g.cache.store
+	c.stored = g.cache.store.share(g.cache.stored)
+	c.recent = list.New()
+	c.elements = make(map[Pos]*list.Element, len(g.cache.elements))
+	for e := g.cache.recent.Front(); e != nil; e = e.Next() {
+		chunkIndex := e.Value.(Pos)
+		c.elements[chunkIndex] = c.recent.PushBack(chunkIndex)
+	}
+	return c
+}
+
+// memoryChunkStore is a ChunkStore that keeps the chunks' states in memory,
+// which are much smaller than the chunks
+type memoryChunkStore map[Pos][]byte
+
+func newMemoryChunkStore() memoryChunkStore {
+	return make(memoryChunkStore)
+}
+
+func (s memoryChunkStore) Load(chunkIndex Pos) ([]byte, error) {
+	return s[chunkIndex], nil
+}
+
+func (s memoryChunkStore) Store(chunkIndex Pos, state []byte) error {
+	s[chunkIndex] = append([]byte(nil), state...)
+	return nil
+}
+
+func (s memoryChunkStore) Delete(chunkIndex Pos) error {
+	delete(s, chunkIndex)
+	return nil
+}
+
+func (s memoryChunkStore) Range(f func(chunkIndex Pos, state []byte) error) error {
+	for chunkIndex, state := range s {
+		err := f(chunkIndex, state)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DirChunkStore is a ChunkStore that keeps each chunk in its own file in a
+// directory. The files are named after the chunks' indexes, so games sharing
+// a directory would overwrite each other's chunks: each game needs its own
+// directory. Other files in the directory are left alone, as are chunk files
+// the game didn't write
+type DirChunkStore struct {
+	dir string
+}
+
+// NewDirChunkStore returns a DirChunkStore that keeps the chunks in the given
+// directory, which is created if it doesn't exist. A game only deletes the
+// chunk files it wrote (such as when it's reset), but it can overwrite any
+// chunk file already in the directory, see DirChunkStore
+func NewDirChunkStore(dir string) (*DirChunkStore, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &DirChunkStore{dir}, nil
+}
+
+// chunkFileName returns the name of the file for the chunk at the given index
+func chunkFileName(chunkIndex Pos) string {
+	return fmt.Sprintf("%d_%d.chunk", chunkIndex.X, chunkIndex.Y)
+}
+
+func (s *DirChunkStore) Load(chunkIndex Pos) ([]byte, error) {
+	state, err := os.ReadFile(filepath.Join(s.dir, chunkFileName(chunkIndex)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(state) != chunkStateSize {
+		return nil, fmt.Errorf("invalid chunk file for %v", chunkIndex)
+	}
+	return state, nil
+}
+
+func (s *DirChunkStore) Store(chunkIndex Pos, state []byte) error {
+	return os.WriteFile(filepath.Join(s.dir, chunkFileName(chunkIndex)),
+		state, 0644)
+}
+
+func (s *DirChunkStore) Delete(chunkIndex Pos) error {
+	err := os.Remove(filepath.Join(s.dir, chunkFileName(chunkIndex)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *DirChunkStore) Range(f func(chunkIndex Pos, state []byte) error) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		// Skip the files that aren't chunks
+		var chunkIndex Pos
+		_, err = fmt.Sscanf(entry.Name(), "%d_%d.chunk",
+			&chunkIndex.X, &chunkIndex.Y)
+		if err != nil || entry.Name() != chunkFileName(chunkIndex) {
+			continue
+		}
+
+		state, err := s.Load(chunkIndex)
+		if err != nil {
+			return err
+		}
+		err = f(chunkIndex, state)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// playAcrossChunks makes moves in chunks far apart from each other, then
+// undoes and redoes some of them
+func playAcrossChunks(game Game) {
+	for i := 0; i < 12; i++ {
+		x, y := (i%4)*3*ChunkSize+8, (i/4)*3*ChunkSize+8
+		game.Uncover(x, y)
+		game.Flag(x+ChunkSize/2, y+ChunkSize/2)
+	}
+	for i := 0; i < 6; i++ {
+		game.Undo()
+	}
+	for i := 0; i < 3; i++ {
+		game.Redo()
+	}
+	game.Uncover(-8, -8)
+}
+
+func testChunkCache(a *assert.Assertions, store ChunkStore) {
+	for seed := int64(0); seed < 5; seed++ {
+		game, err := NewInfiniteGame(40, WithSeed(seed))
+		a.NoError(err)
+		cachedGame, err := NewInfiniteGame(40, WithSeed(seed),
+			WithChunkCache(4, store))
+		a.NoError(err)
+		cached := cachedGame.(*InfiniteGame)
+
+		// The events load the chunks next to the changed tiles too
+		cached.Subscribe(func(Event) {})
+		playAcrossChunks(game)
+		playAcrossChunks(cached)
+		a.LessOrEqual(len(cached.field), 4)
+		a.NoError(cached.ChunkStoreErr())
+
+		// But the game should be the same as one without a cache
+		expected := game.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize)
+		a.Equal(expected, cached.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize))
+		a.LessOrEqual(len(cached.field), 4)
+		a.Equal(game.(*InfiniteGame).Score(), cached.Score())
+		a.Equal(game.State(), cached.State())
+
+		// Including the spilled chunks in the save
+		var buf bytes.Buffer
+		a.NoError(cached.Save(&buf))
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+		a.Equal(expected,
+			loadedGame.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize))
+		a.Equal(game.(*InfiniteGame).Score(), loadedGame.(*InfiniteGame).Score())
+
+		// Resetting removes the spilled chunks
+		a.NoError(cached.Reset(40))
+		stored := 0
+		a.NoError(store.Range(func(Pos, []byte) error {
+			stored++
+			return nil
+		}))
+		a.Equal(0, stored)
+	}
+}
+
+func TestChunkCacheMemory(t *testing.T) {
+	testChunkCache(assert.New(t), newMemoryChunkStore())
+}
+
+func TestChunkCacheDir(t *testing.T) {
+	a := assert.New(t)
+
+	store, err := NewDirChunkStore(t.TempDir())
+	a.NoError(err)
+	testChunkCache(a, store)
+}
+
+func TestChunkCacheSharedDir(t *testing.T) {
+	a := assert.New(t)
+
+	// A chunk that another game stored, and a file that isn't a chunk
+	dir := t.TempDir()
+	store, err := NewDirChunkStore(dir)
+	a.NoError(err)
+	state := bytes.Repeat([]byte{0xff}, chunkStateSize)
+	a.NoError(store.Store(Pos{100, 100}, state))
+	notes := filepath.Join(dir, "notes.txt")
+	a.NoError(os.WriteFile(notes, []byte("notes"), 0644))
+
+	game, err := NewInfiniteGame(40, WithSeed(1), WithChunkCache(4, store))
+	a.NoError(err)
+	playAcrossChunks(game)
+	a.NoError(game.(*InfiniteGame).ChunkStoreErr())
+
+	// The game doesn't see the other chunk
+	fresh, err := NewInfiniteGame(40, WithSeed(1))
+	a.NoError(err)
+	a.Equal(fresh.Appearance(100*ChunkSize, 100*ChunkSize, ChunkSize, ChunkSize),
+		game.Appearance(100*ChunkSize, 100*ChunkSize, ChunkSize, ChunkSize))
+
+	// Or delete it when it's reset
+	a.NoError(game.Reset(40))
+	var stored []Pos
+	a.NoError(store.Range(func(chunkIndex Pos, _ []byte) error {
+		stored = append(stored, chunkIndex)
+		return nil
+	}))
+	a.Equal([]Pos{{100, 100}}, stored)
+	_, err = os.Stat(notes)
+	a.NoError(err)
+}
+
+func TestChunkCacheClone(t *testing.T) {
+	a := assert.New(t)
+
+	store := newMemoryChunkStore()
+	game, err := NewInfiniteGame(40, WithSeed(1), WithChunkCache(2, store))
+	a.NoError(err)
+	playAcrossChunks(game)
+	reference, err := NewInfiniteGame(40, WithSeed(1))
+	a.NoError(err)
+	playAcrossChunks(reference)
+
+	// The clone shares the spilled chunks instead of copying them
+	numStored := len(store)
+	clone := game.Clone()
+	a.Len(store, numStored)
+	for _, chunk := range clone.(*InfiniteGame).cache.stored {
+		a.Nil(chunk.state)
+	}
+	appearance := game.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize)
+	a.Equal(appearance, clone.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize))
+
+	// But the games can change them without changing each other
+	referenceClone := reference.Clone()
+	for _, g := range []Game{game, reference} {
+		g.Undo()
+		g.Undo()
+		g.Uncover(3*ChunkSize+8, 3*ChunkSize+8)
+	}
+	// Only the chunks the game replaced are copied for the clone
+	copied := 0
+	for _, chunk := range clone.(*InfiniteGame).cache.stored {
+		if chunk.state != nil {
+			copied++
+		}
+	}
+	a.NotZero(copied)
+	a.Less(copied, numStored)
+	for _, g := range []Game{clone, referenceClone} {
+		g.Flag(6*ChunkSize+8, 3*ChunkSize+8)
+		g.Uncover(9*ChunkSize+8, 6*ChunkSize+8)
+	}
+	a.Equal(reference.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize),
+		game.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize))
+	a.Equal(referenceClone.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize),
+		clone.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize))
+	a.NoError(game.(*InfiniteGame).ChunkStoreErr())
+	a.NoError(clone.(*InfiniteGame).ChunkStoreErr())
+
+	// Resetting one game leaves the other's chunks in the store
+	a.NoError(clone.Reset(40))
+	a.NotEmpty(store)
+	a.Equal(reference.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize),
+		game.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize))
+	a.NoError(game.Reset(40))
+	a.Empty(store)
+}
+
+func TestSetChunkCache(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewInfiniteGame(40, WithSeed(1))
+	a.NoError(err)
+	g := game.(*InfiniteGame)
+	playAcrossChunks(g)
+	appearance := g.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize)
+
+	// Loaded games can be given a cache
+	store := newMemoryChunkStore()
+	a.NoError(g.SetChunkCache(2, store))
+	a.Len(g.field, 2)
+	a.NotEmpty(store)
+
+	// The chunks are moved to a new store
+	newStore := newMemoryChunkStore()
+	a.NoError(g.SetChunkCache(2, newStore))
+	a.Empty(store)
+	a.NotEmpty(newStore)
+
+	// Or back into memory
+	a.NoError(g.SetChunkCache(0, nil))
+	a.Empty(newStore)
+	a.Equal(appearance, g.Appearance(-16, -16, 12*ChunkSize, 10*ChunkSize))
+
+	a.Error(g.SetChunkCache(-1, nil))
+	a.Error(g.SetChunkCache(2, nil))
+	_, err = NewGame(16, 16, 40, WithChunkCache(2, newMemoryChunkStore()))
+	a.Error(err)
+}
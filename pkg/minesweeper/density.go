@@ -0,0 +1,239 @@
+package minesweeper
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Density varies the number of mines in each chunk of an infinite game, see
+// WithDensity. Any type can be a density, but only DensityGradient and
+// DensityBiomes can be saved with the game: saving a game with any other
+// density returns ErrCustomDensity, and the game isn't recorded (its Replay is
+// nil)
+type Density interface {
+	// Mines returns the number of mines in the chunk at the given index (the
+	// chunk's position divided by ChunkSize), given the game's mine density.
+	// The game keeps the number between 0 and the most mines that fit. It
+	// must always return the same number for the same chunk
+	Mines(chunk Pos, mineDensity int) int
+}
+
+// ErrCustomDensity is returned when saving an infinite game with a density
+// that isn't a DensityGradient or DensityBiomes
+var ErrCustomDensity = errors.New("custom densities can't be saved")
+
+// builtinDensity is a density that can be validated and saved
+type builtinDensity interface {
+	Density
+	validate() error
+	save(w io.Writer) error
+}
+
+// validateDensity returns an error if the density is invalid. Custom
+// densities can't be checked, so they're always valid
+func validateDensity(d Density) error {
+	if builtin, ok := d.(builtinDensity); ok {
+		return builtin.validate()
+	}
+	return nil
+}
+
+// WithDensity varies the number of mines in each chunk of an infinite game,
+// instead of every chunk having the game's mine density
+func WithDensity(d Density) Option {
+	return func(o *options) {
+		o.density = d
+	}
+}
+
+// DensityGradient makes an infinite game harder the further it's played from
+// the origin. The chunk at the origin has the game's mine density, and every
+// ring of chunks around it has Step more mines than the ring inside it, up to
+// Max (or 0 for as many as fit). Step can be negative to make the game easier
+type DensityGradient struct {
+	Step float64
+	Max  int
+}
+
+func (d DensityGradient) Mines(chunk Pos, mineDensity int) int {
+	// The index of the chunks before the origin starts at -1, so they're the
+	// first ring too
+	distance := max(abs(chunk.X), abs(chunk.Y))
+	mines := mineDensity + int(math.Round(d.Step*float64(distance)))
+	if d.Max > 0 {
+		mines = min(mines, d.Max)
+	}
+	return mines
+}
+
+func (d DensityGradient) validate() error {
+	if d.Max < 0 {
+		return fmt.Errorf("invalid maximum number of mines %d", d.Max)
+	}
+	return nil
+}
+
+// DensityBiomes makes areas of dense and sparse chunks in an infinite game.
+// The areas are roughly Size chunks across, and their density varies smoothly
+// by up to Variation times the game's mine density either way. Games with the
+// same Seed have the same areas
+type DensityBiomes struct {
+	Size      int
+	Variation float64
+	Seed      int64
+}
+
+func (d DensityBiomes) Mines(chunk Pos, mineDensity int) int {
+	// Find the corners of the area the chunk is in, and how far the chunk is
+	// from the top left corner
+	x, y := floorDiv(chunk.X, d.Size), floorDiv(chunk.Y, d.Size)
+	fx := smoothStep(float64(mod(chunk.X, d.Size)) / float64(d.Size))
+	fy := smoothStep(float64(mod(chunk.Y, d.Size)) / float64(d.Size))
+
+	// Blend the noise at the corners
+	top := lerp(d.noise(x, y), d.noise(x+1, y), fx)
+	bottom := lerp(d.noise(x, y+1), d.noise(x+1, y+1), fx)
+	noise := lerp(top, bottom, fy)
+
+	// Scale the noise from [0, 1) to [-Variation, Variation)
+	scale := 1 + d.Variation*(2*noise-1)
+	return int(math.Round(float64(mineDensity) * scale))
+}
+
+// noise returns a random number in [0, 1) for the given corner of an area
+func (d DensityBiomes) noise(x, y int) float64 {
+	// Use the top 53 bits, which is as many as a float64 can hold
+	return float64(hashPos(d.Seed, Pos{x, y})>>11) / (1 << 53)
+}
+
+func (d DensityBiomes) validate() error {
+	if d.Size < 1 {
+		return fmt.Errorf("invalid biome size %d", d.Size)
+	}
+	if d.Variation < 0 {
+		return fmt.Errorf("invalid biome variation %f", d.Variation)
+	}
+	return nil
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// floorDiv divides a by b, rounding down instead of towards 0
+func floorDiv(a, b int) int {
+	return (a - mod(a, b)) / b
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// smoothStep eases t in [0, 1], so the blend between areas doesn't have
+// corners
+func smoothStep(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
+
+// The types of density in a save
+const (
+	densityUniformType = uint8(iota)
+	densityGradientType
+	densityBiomesType
+)
+
+func (d DensityGradient) save(w io.Writer) error {
+	err := binary.Write(w, serialiseByteOrder, densityGradientType)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(w, serialiseByteOrder, d.Step)
+	if err != nil {
+		return err
+	}
+	return binary.Write(w, serialiseByteOrder, int64(d.Max))
+}
+
+func (d DensityBiomes) save(w io.Writer) error {
+	err := binary.Write(w, serialiseByteOrder, densityBiomesType)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(w, serialiseByteOrder, int64(d.Size))
+	if err != nil {
+		return err
+	}
+	err = binary.Write(w, serialiseByteOrder, d.Variation)
+	if err != nil {
+		return err
+	}
+	return binary.Write(w, serialiseByteOrder, d.Seed)
+}
+
+// canSaveDensity returns whether the density can be saved with the game
+func canSaveDensity(d Density) bool {
+	_, ok := d.(builtinDensity)
+	return d == nil || ok
+}
+
+// saveDensity writes the density, where nil is the game's mine density in
+// every chunk
+func saveDensity(w io.Writer, d Density) error {
+	if d == nil {
+		return binary.Write(w, serialiseByteOrder, densityUniformType)
+	}
+	builtin, ok := d.(builtinDensity)
+	if !ok {
+		return ErrCustomDensity
+	}
+	return builtin.save(w)
+}
+
+func loadDensity(r io.Reader) (Density, error) {
+	var densityType uint8
+	err := binary.Read(r, serialiseByteOrder, &densityType)
+	if err != nil {
+		return nil, err
+	}
+
+	var d builtinDensity
+	switch densityType {
+	case densityUniformType:
+		return nil, nil
+	case densityGradientType:
+		var gradient DensityGradient
+		var max int64
+		err = binary.Read(r, serialiseByteOrder, &gradient.Step)
+		if err == nil {
+			err = binary.Read(r, serialiseByteOrder, &max)
+		}
+		// todo int overflow
+		gradient.Max = int(max)
+		d = gradient
+	case densityBiomesType:
+		var biomes DensityBiomes
+		var size int64
+		err = binary.Read(r, serialiseByteOrder, &size)
+		if err == nil {
+			err = binary.Read(r, serialiseByteOrder, &biomes.Variation)
+		}
+		if err == nil {
+			err = binary.Read(r, serialiseByteOrder, &biomes.Seed)
+		}
+		// todo int overflow
+		biomes.Size = int(size)
+		d = biomes
+	default:
+		return nil, fmt.Errorf("unknown density type %d", densityType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d, d.validate()
+}
@@ -0,0 +1,131 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDensityGradient(t *testing.T) {
+	a := assert.New(t)
+
+	d := DensityGradient{Step: 2, Max: 30}
+	a.Equal(10, d.Mines(Pos{0, 0}, 10))
+	a.Equal(12, d.Mines(Pos{-1, 0}, 10))
+	a.Equal(16, d.Mines(Pos{3, -2}, 10))
+	a.Equal(30, d.Mines(Pos{100, 0}, 10))
+
+	// It can get easier too
+	a.Equal(4, DensityGradient{Step: -2}.Mines(Pos{0, 3}, 10))
+}
+
+func TestDensityBiomes(t *testing.T) {
+	a := assert.New(t)
+
+	d := DensityBiomes{Size: 4, Variation: 0.5, Seed: 1}
+	counts := make(map[int]bool)
+	for y := -20; y < 20; y++ {
+		for x := -20; x < 20; x++ {
+			mines := d.Mines(Pos{x, y}, 40)
+			a.GreaterOrEqual(mines, 20)
+			a.LessOrEqual(mines, 60)
+			counts[mines] = true
+
+			// The density changes smoothly
+			a.InDelta(mines, d.Mines(Pos{x + 1, y}, 40), 15)
+		}
+	}
+	// There are dense and sparse areas
+	a.Greater(len(counts), 10)
+
+	// The same seed makes the same areas
+	a.Equal(d.Mines(Pos{7, -3}, 40),
+		DensityBiomes{Size: 4, Variation: 0.5, Seed: 1}.Mines(Pos{7, -3}, 40))
+}
+
+// countChunkMines counts the mines in the chunk at the given index
+func countChunkMines(g *InfiniteGame, chunkIndex Pos) int {
+	return g.getChunk(chunkIndex).mines.count()
+}
+
+func TestInfiniteDensity(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewInfiniteGame(10, WithSeed(1),
+		WithDensity(DensityGradient{Step: 20}))
+	a.NoError(err)
+	g := game.(*InfiniteGame)
+	g.Uncover(8, 8)
+
+	// The chunks get denser away from the origin, until they're full
+	a.Equal(10, countChunkMines(g, Pos{0, 0}))
+	a.Equal(110, countChunkMines(g, Pos{5, 0}))
+	a.Equal(ChunkSize*ChunkSize-9, countChunkMines(g, Pos{0, -20}))
+
+	// The density should survive being saved, so the chunks are generated the
+	// same way
+	var buf bytes.Buffer
+	a.NoError(g.Save(&buf))
+	loadedGame, err := Load(&buf)
+	a.NoError(err)
+	loaded := loadedGame.(*InfiniteGame)
+	a.Equal(g.density, loaded.density)
+	a.Equal(countChunkMines(g, Pos{3, 3}), countChunkMines(loaded, Pos{3, 3}))
+	a.Equal(g.field[Pos{3, 3}], loaded.field[Pos{3, 3}])
+
+	// And so should biomes
+	game, err = NewInfiniteGame(40, WithSeed(1),
+		WithDensity(DensityBiomes{Size: 4, Variation: 0.5, Seed: 2}))
+	a.NoError(err)
+	buf.Reset()
+	a.NoError(game.Save(&buf))
+	loadedGame, err = Load(&buf)
+	a.NoError(err)
+	a.Equal(game.(*InfiniteGame).density, loadedGame.(*InfiniteGame).density)
+}
+
+// stripes is a custom density with columns of full chunks
+type stripes struct{}
+
+func (stripes) Mines(chunk Pos, mineDensity int) int {
+	if mod(chunk.X, 2) == 0 {
+		return mineDensity
+	}
+	return ChunkSize * ChunkSize
+}
+
+func TestCustomDensity(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewInfiniteGame(10, WithSeed(1), WithDensity(stripes{}))
+	a.NoError(err)
+	g := game.(*InfiniteGame)
+	g.Uncover(8, 8)
+
+	// The game keeps the number of mines in range
+	a.Equal(10, g.ChunkMines(Pos{2, 0}))
+	a.Equal(ChunkSize*ChunkSize-9, g.ChunkMines(Pos{-1, 3}))
+	a.Equal(10, countChunkMines(g, Pos{0, 0}))
+	a.Equal(ChunkSize*ChunkSize-9, countChunkMines(g, Pos{1, 0}))
+
+	// But it can't be saved
+	var buf bytes.Buffer
+	a.ErrorIs(g.Save(&buf), ErrCustomDensity)
+	a.Zero(buf.Len())
+	a.Nil(g.Replay())
+}
+
+func TestDensityValidate(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := NewInfiniteGame(40, WithDensity(DensityGradient{Max: -1}))
+	a.Error(err)
+	_, err = NewInfiniteGame(40, WithDensity(DensityBiomes{Size: 0}))
+	a.Error(err)
+	_, err = NewInfiniteGame(40, WithDensity(DensityBiomes{Size: 1, Variation: -1}))
+	a.Error(err)
+
+	// Finite games place all their mines at once
+	_, err = NewGame(16, 16, 40, WithDensity(DensityGradient{Step: 1}))
+	a.Error(err)
+}
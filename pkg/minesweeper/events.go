@@ -0,0 +1,147 @@
+package minesweeper
+
+// Event is something that happened in a game, see Game.Subscribe. It's one of
+// TilesRevealedEvent, TilesHiddenEvent, FlagToggledEvent, MineHitEvent or
+// StateChangedEvent
+type Event interface {
+	isEvent()
+}
+
+// TilesRevealedEvent is emitted when tiles are uncovered
+type TilesRevealedEvent struct {
+	// The appearance of the uncovered tiles
+	Tiles map[Pos]TileType
+}
+
+// TilesHiddenEvent is emitted when uncovered tiles are covered again, by
+// undoing a move or resetting the game
+type TilesHiddenEvent struct {
+	// The appearance of the covered tiles, either TileTypeHidden or
+	// TileTypeFlag
+	Tiles map[Pos]TileType
+}
+
+// FlagToggledEvent is emitted when a tile is flagged or unflagged, or its
+// number of flags changes
+type FlagToggledEvent struct {
+	Pos
+	Flagged bool
+
+	// The number of flags on the tile, which is only more than 1 in multimine
+	// games, see WithMultiMines
+	Flags int
+}
+
+// MineHitEvent is emitted when a mine is uncovered by a move
+type MineHitEvent struct {
+	Pos
+}
+
+// StateChangedEvent is emitted when the game's state changes
+type StateChangedEvent struct {
+	Before, After GameState
+}
+
+func (TilesRevealedEvent) isEvent() {}
+func (TilesHiddenEvent) isEvent()   {}
+func (FlagToggledEvent) isEvent()   {}
+func (MineHitEvent) isEvent()       {}
+func (StateChangedEvent) isEvent()  {}
+
+type subscriber struct {
+	id int
+	f  func(Event)
+}
+
+// observers stores the functions subscribed to a game's events
+type observers struct {
+	nextID      int
+	subscribers []subscriber
+}
+
+// subscribe adds f to the subscribers, and returns a function to remove it
+func (o *observers) subscribe(f func(Event)) (unsubscribe func()) {
+	id := o.nextID
+	o.nextID++
+	o.subscribers = append(o.subscribers, subscriber{id, f})
+	return func() {
+		// Create a new slice, so the subscribers can unsubscribe while an
+		// event is being emitted
+		subscribers := make([]subscriber, 0, len(o.subscribers))
+		for _, s := range o.subscribers {
+			if s.id != id {
+				subscribers = append(subscribers, s)
+			}
+		}
+		o.subscribers = subscribers
+	}
+}
+
+// active returns true if anything is subscribed, so events only need to be
+// worked out if it's true
+func (o *observers) active() bool {
+	return len(o.subscribers) > 0
+}
+
+// emit passes each of the events to every subscriber in turn. Subscribers
+// removed while an event is being emitted don't receive the next events
+func (o *observers) emit(events ...Event) {
+	for _, e := range events {
+		for _, s := range o.subscribers {
+			s.f(e)
+		}
+	}
+}
+
+// moveEvents collects the events caused by a move, so they can be emitted in
+// a consistent order once the move has finished
+type moveEvents struct {
+	revealed, hidden map[Pos]TileType
+	flags, mineHits  []Event
+}
+
+// tile adds the events for a tile that was changed, given whether it was
+// discovered and the number of flags on it before and after, and its
+// appearance afterwards
+func (e *moveEvents) tile(p Pos, wasDiscovered, discovered bool,
+	wasFlags, flags int, appearance TileType) {
+	if wasDiscovered != discovered {
+		if discovered {
+			if e.revealed == nil {
+				e.revealed = make(map[Pos]TileType)
+			}
+			e.revealed[p] = appearance
+		} else {
+			if e.hidden == nil {
+				e.hidden = make(map[Pos]TileType)
+			}
+			e.hidden[p] = appearance
+		}
+	}
+	if wasFlags != flags {
+		e.flags = append(e.flags, FlagToggledEvent{p, flags > 0, flags})
+	}
+}
+
+func (e *moveEvents) mineHit(p Pos) {
+	e.mineHits = append(e.mineHits, MineHitEvent{p})
+}
+
+// emit the collected events to the observers, followed by the change in the
+// game's state (if there was one), and clear them
+func (e *moveEvents) emit(o *observers, before, after GameState) {
+	var events []Event
+	if len(e.revealed) > 0 {
+		events = append(events, TilesRevealedEvent{e.revealed})
+	}
+	if len(e.hidden) > 0 {
+		events = append(events, TilesHiddenEvent{e.hidden})
+	}
+	events = append(events, e.flags...)
+	events = append(events, e.mineHits...)
+	if before != after {
+		events = append(events, StateChangedEvent{before, after})
+	}
+	*e = moveEvents{}
+	o.emit(events...)
+}
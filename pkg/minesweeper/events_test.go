@@ -0,0 +1,149 @@
+package minesweeper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// appearanceFromEvents keeps an appearance up to date with a game's events
+type appearanceFromEvents struct {
+	appearance map[Pos]TileType
+	state      GameState
+	events     []Event
+}
+
+func (a *appearanceFromEvents) apply(e Event) {
+	a.events = append(a.events, e)
+	switch e := e.(type) {
+	case TilesRevealedEvent:
+		for pos, tileType := range e.Tiles {
+			a.set(pos, tileType)
+		}
+	case TilesHiddenEvent:
+		for pos, tileType := range e.Tiles {
+			a.set(pos, tileType)
+		}
+	case FlagToggledEvent:
+		if e.Flagged {
+			a.set(e.Pos, TileTypeFlag)
+		} else {
+			a.set(e.Pos, TileTypeHidden)
+		}
+	case StateChangedEvent:
+		a.state = e.After
+	}
+}
+
+func (a *appearanceFromEvents) set(pos Pos, tileType TileType) {
+	// Only keep track of the tiles in the appearance's rect
+	if _, ok := a.appearance[pos]; ok {
+		a.appearance[pos] = tileType
+	}
+}
+
+func testEvents(a *assert.Assertions, game Game, x, y, w, h int) {
+	tracked := &appearanceFromEvents{
+		appearance: game.Appearance(x, y, w, h),
+		state:      game.State(),
+	}
+	unsubscribe := game.Subscribe(tracked.apply)
+
+	moves := []func(){
+		func() { game.Flag(0, 0) },
+		func() { game.Uncover(8, 8) },
+		func() { game.Flag(0, 0) },
+		func() { game.Chord(8, 8) },
+		func() { game.Undo() },
+		func() { game.Redo() },
+		func() { game.Undo() },
+		func() { game.Undo() },
+		func() { game.Redo() },
+	}
+	// Uncover the tiles in the top left until a mine is hit
+	for tileY := 0; tileY < 16; tileY++ {
+		for tileX := 0; tileX < 16; tileX++ {
+			tileX, tileY := tileX, tileY
+			moves = append(moves, func() { game.Uncover(tileX, tileY) })
+		}
+	}
+	moves = append(moves, func() { game.Undo() })
+	moves = append(moves, func() { a.NoError(game.Reset(40)) })
+
+	for _, move := range moves {
+		move()
+		a.Equal(game.Appearance(x, y, w, h), tracked.appearance)
+		a.Equal(game.State(), tracked.state)
+	}
+
+	// Nothing should be emitted after unsubscribing
+	unsubscribe()
+	numEvents := len(tracked.events)
+	game.Uncover(8, 8)
+	game.Flag(0, 0)
+	a.Len(tracked.events, numEvents)
+}
+
+func TestFiniteEvents(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewGame(16, 16, 40, WithSeed(seed))
+		a.NoError(err)
+		testEvents(a, game, 0, 0, 16, 16)
+	}
+}
+
+func TestInfiniteEvents(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 5; seed++ {
+		game, err := NewInfiniteGame(40, WithSeed(seed))
+		a.NoError(err)
+		testEvents(a, game, -8, -8, 32, 32)
+	}
+}
+
+func TestEventsMineHit(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame(16, 16, 40, WithSeed(1))
+	a.NoError(err)
+	game.Uncover(8, 8)
+	mine := findMine(game.(*FiniteGame))
+
+	var events []Event
+	game.Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+	game.Uncover(mine.X, mine.Y)
+
+	// The mines are revealed, then the mine is hit and the game is lost
+	a.Len(events, 3)
+	a.IsType(TilesRevealedEvent{}, events[0])
+	a.Equal(MineHitEvent{mine}, events[1])
+	a.Equal(StateChangedEvent{GameStatePlaying, GameStateLoss}, events[2])
+}
+
+func TestEventsUnsubscribeWhileEmitting(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame(16, 16, 40, WithSeed(1))
+	a.NoError(err)
+
+	calls := 0
+	var unsubscribe func()
+	unsubscribe = game.Subscribe(func(Event) {
+		calls++
+		unsubscribe()
+	})
+	otherCalls := 0
+	game.Subscribe(func(Event) {
+		otherCalls++
+	})
+
+	// The first move reveals tiles and starts the game, but the first
+	// subscriber only sees the first event
+	game.Uncover(8, 8)
+	a.Equal(1, calls)
+	a.Equal(2, otherCalls)
+}
@@ -1,30 +1,160 @@
 package minesweeper
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
+	"sort"
 	"time"
 )
 
 // FiniteGame stores a finite minesweeper game
 type FiniteGame struct {
 	w, h, numMines int
+	topology       topology
 	field          Field
 	state          GameState
 	startTime      time.Time
 	flags          int
+	source         *countingSource
+	rng            *rand.Rand
+	noGuess        noGuessOptions
+	history        history
+
+	// The error from generating a no-guess field for the first move, if
+	// Uncover fell back to a field that might need guessing
+	noGuessErr error
+	replay     *Replay
+
+	// The number of random values drawn before the mines were placed, which
+	// is recorded in the replay so it can place the same mines
+	fieldDraws uint64
+
+	// Which tiles are kept free of mines for the first move, see
+	// WithFirstMovePolicy
+	firstMovePolicy firstMoveOptions
+
+	// The most mines a tile can have, see WithMultiMines
+	maxMines int
+
+	// The number of lives the game starts with and has left, see WithLives,
+	// and the number of mines in the tiles that have exploded
+	startingLives, lives int
+	exploded             int
+
+	// The tiles changed by the move being made (before they were changed),
+	// and the game before the move
+	changed    map[Pos]Tile
+	moveBefore finiteSnapshot
+
+	// The functions subscribed to the game's events, and the events caused by
+	// the move being made
+	observers observers
+	events    moveEvents
 }
 
 // NewGame creates a new, finite minesweeper game
-func NewGame(width int, height int, numMines int) (Game, error) {
+func NewGame(width int, height int, numMines int, opts ...Option) (Game, error) {
+	g, err := newFiniteGame(width, height, numMines,
+		topology{tiling: tilingSquare, depth: 1}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// newFiniteGame creates a finite game with the given topology, which is
+// completed by the options
+func newFiniteGame(width, height, numMines int, t topology,
+	opts []Option) (*FiniteGame, error) {
+	o := newOptions(opts)
+	t.wrap = o.toroidal
+	t.neighbourhood = o.neighbourhood
+
+	// The rows of a hex field alternate, so an odd number of rows can't wrap
+	if t.tiling == tilingHex && t.wrap && height%2 != 0 {
+		return nil, fmt.Errorf(
+			"a hex field can only wrap with an even height, not %d", height)
+	}
+
+	if o.neighbourhood != nil {
+		// The neighbours of a hex tile depend on its row, so they can't be
+		// given as offsets
+		if t.tiling != tilingSquare {
+			return nil, fmt.Errorf(
+				"neighbourhoods can only be used with a grid of squares")
+		}
+		err := o.neighbourhood.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if o.maxMines < 1 {
+		return nil, fmt.Errorf("invalid number of mines per tile %d", o.maxMines)
+	}
+	if o.maxMines > 1 {
+		// The solver doesn't know about multimines, so it can't tell whether
+		// a field needs guessing
+		if o.noGuess.enabled {
+			return nil, fmt.Errorf("multimines can't be used with no-guess fields")
+		}
+		// The numbers need to fit in a tile's type
+		if TileTypeNumber(o.maxMines*t.maxNeighbours()) > math.MaxUint8 {
+			return nil, fmt.Errorf(
+				"too many mines per tile (%d) for %d neighbours",
+				o.maxMines, t.maxNeighbours())
+		}
+	}
+
+	err := o.noGuess.validate()
+	if err != nil {
+		return nil, err
+	}
+	err = o.firstMove.validate()
+	if err != nil {
+		return nil, err
+	}
+	// The solver starts from the first move, so it can't be a mine
+	if o.noGuess.enabled && o.firstMove.policy == FirstMoveUnprotected {
+		return nil, fmt.Errorf(
+			"no-guess fields can't be used with an unprotected first move")
+	}
+
+	lives, err := o.startingLives(1)
+	if err != nil {
+		return nil, err
+	}
+	err = o.validateLimits(false)
+	if err != nil {
+		return nil, err
+	}
+	if o.density != nil {
+		return nil, fmt.Errorf("densities can only be used with infinite games")
+	}
+	if o.maxChunks != 0 || o.chunkStore != nil {
+		return nil, fmt.Errorf("chunk caches can only be used with infinite games")
+	}
+
 	// Create the game object
 	g := &FiniteGame{
-		w: width,
-		h: height,
+		w:               width,
+		h:               height,
+		topology:        t,
+		noGuess:         o.noGuess,
+		history:         history{policy: o.undoPolicy},
+		firstMovePolicy: o.firstMove,
+		maxMines:        o.maxMines,
+		startingLives:   lives,
 	}
 
+	// Create the random number generator
+	g.source = newCountingSource(o.seed, 0)
+	g.rng = rand.New(g.source)
+
 	// Create the grid
 	g.field = make(Field, g.h)
 	for y := 0; y < g.h; y++ {
@@ -32,7 +162,7 @@ func NewGame(width int, height int, numMines int) (Game, error) {
 	}
 
 	// Reset the game
-	err := g.Reset(numMines)
+	err = g.Reset(numMines)
 	if err != nil {
 		return nil, err
 	}
@@ -41,35 +171,72 @@ func NewGame(width int, height int, numMines int) (Game, error) {
 }
 
 func (g *FiniteGame) Reset(numMines int) error {
-	// Sanity check
-	if numMines > (g.w*g.h)-9 {
+	if numMines < 0 {
+		return fmt.Errorf("invalid number of mines %d", numMines)
+	}
+	// Sanity check, there needs to be room for the tiles kept free of mines
+	// for the first move, which can be the whole field
+	safeTiles := min(g.firstMovePolicy.safeTiles(g.topology.maxNeighbours()),
+		g.w*g.h)
+	if numMines > ((g.w*g.h)-safeTiles)*g.maxMines {
 		return fmt.Errorf(
-			"too many mines! numMines (%d) > width (%d) * height (%d) - 9",
-			numMines, g.w, g.h)
+			"too many mines! numMines (%d) > (width (%d) * height (%d) - %d) * %d",
+			numMines, g.w, g.h, safeTiles, g.maxMines)
 	}
 
 	// Set the number of mines
 	g.numMines = numMines
+	g.noGuessErr = nil
+	// Restore the lives
+	g.lives = g.startingLives
+	g.exploded = 0
 	// Set the game state
+	before := g.state
 	g.state = GameStateStart
+	// Forget the previous game's moves
+	g.history.clear()
+
+	// Start recording the new game
+	err := g.startRecording()
+	if err != nil {
+		return err
+	}
 
+	// The discovered tiles are hidden until the next game starts
+	if g.observers.active() {
+		for y, row := range g.field {
+			for x, tile := range row {
+				if tile.Discovered {
+					g.events.tile(Pos{x, y}, true, false,
+						int(tile.Flags), int(tile.Flags), g.tileAppearance(x, y))
+				}
+			}
+		}
+	}
+	g.events.emit(&g.observers, before, g.state)
 	return nil
 }
 
 func (g *FiniteGame) Uncover(x, y int) (s GameState) {
+	g.record(ActionUncover, x, y)
+	g.beginMove()
 	defer func() {
+		g.endMove()
 		// The return value is the game's state
 		s = g.state
 	}()
 
-	// If the game hasn't started yet
-	if g.state == GameStateStart {
+	// If the game hasn't started yet (and the move is on the field)
+	if g.state == GameStateStart && x >= 0 && x < g.w && y >= 0 && y < g.h {
 		// Populate the field
-		g.populateField(x, y)
-		// Set the start time
-		g.startTime = time.Now()
-		// Set the game as started
-		g.state = GameStatePlaying
+		err := g.Populate(x, y)
+		// If a no-guess field couldn't be generated, fall back to a field
+		// that might need guessing
+		if err != nil {
+			g.noGuessErr = err
+			g.populateField(x, y)
+			g.start()
+		}
 	}
 
 	// If the x or y is out of range, the cell is flagged, the call is already
@@ -83,13 +250,26 @@ func (g *FiniteGame) Uncover(x, y int) (s GameState) {
 
 	// If the tile is a mine
 	if g.field[y][x].Type == TileTypeMine {
+		g.events.mineHit(Pos{x, y})
+		// The mine explodes and costs a life
+		tile := g.modify(x, y)
+		tile.Discovered = true
+		tile.Exploded = true
+		g.exploded += int(tile.Mines)
+		if g.lives != UnlimitedLives {
+			g.lives--
+		}
+		// If there are lives left, the game carries on
+		if g.lives != 0 {
+			return
+		}
 		// Find the mines
 		for y2, row := range g.field {
 			for x2, tile := range row {
 				// If the tile is a mine
 				if tile.Type == TileTypeMine {
 					// Set the tile as discovered
-					g.field[y2][x2].Discovered = true
+					g.modify(x2, y2).Discovered = true
 				}
 			}
 		}
@@ -108,7 +288,7 @@ func (g *FiniteGame) Uncover(x, y int) (s GameState) {
 		queue = queue[:len(queue)-1]
 
 		// Set the tile as discovered
-		g.field[pos.Y][pos.X].Discovered = true
+		g.modify(pos.X, pos.Y).Discovered = true
 
 		// If the tile is empty
 		if g.field[pos.Y][pos.X].Type == TileTypeEmpty {
@@ -140,7 +320,116 @@ func (g *FiniteGame) Uncover(x, y int) (s GameState) {
 	return
 }
 
+func (g *FiniteGame) Chord(x, y int) GameState {
+	g.record(ActionChord, x, y)
+
+	// If the x or y is out of range, the tile isn't a discovered number, or
+	// the game isn't being played
+	if x < 0 || x >= g.w || y < 0 || y >= g.h ||
+		!g.field[y][x].Discovered || g.field[y][x].Type == TileTypeMine ||
+		g.state != GameStatePlaying {
+		// Nothing needs to be done, so just return the game's state
+		return g.state
+	}
+
+	g.beginMove()
+	defer g.endMove()
+
+	// Count the neighbouring flags, and the mines that have exploded
+	neighbouringTiles := g.neighbouringTiles(x, y)
+	flags := 0
+	for _, tile := range neighbouringTiles {
+		flags += int(tile.Flags)
+		if tile.Exploded {
+			flags += int(tile.Mines)
+		}
+	}
+
+	// If the flags don't match the number, nothing happens
+	if number, _ := g.field[y][x].Type.Number(); flags != number {
+		return g.state
+	}
+
+	// Uncover the unflagged neighbours (Uncover skips the flagged ones)
+	for _, tile := range neighbouringTiles {
+		if g.Uncover(tile.X, tile.Y) != GameStatePlaying {
+			break
+		}
+	}
+
+	return g.state
+}
+
+// Populate places the mines as if the first move is at the given coordinate,
+// and starts the game. Uncover calls this automatically for the first move,
+// but it can be called beforehand to find out whether a no-guess field could
+// be generated (see WithNoGuess). The coordinate must be on the field. If an
+// error is returned, the game is left in GameStateStart. Calls are recorded
+// in the game's replay, like moves
+func (g *FiniteGame) Populate(x, y int) error {
+	g.record(ActionPopulate, x, y)
+	if g.state != GameStateStart {
+		return fmt.Errorf("game has already started")
+	}
+	if x < 0 || x >= g.w || y < 0 || y >= g.h {
+		return fmt.Errorf("position (%d, %d) is out of range", x, y)
+	}
+
+	if g.noGuess.enabled {
+		err := g.populateNoGuessField(x, y)
+		if err != nil {
+			return err
+		}
+	} else {
+		g.populateField(x, y)
+	}
+
+	g.start()
+	// If the game wasn't populated by a move, the state change needs to be
+	// emitted now
+	if g.history.depth == 0 {
+		g.observers.emit(StateChangedEvent{GameStateStart, g.state})
+	}
+	return nil
+}
+
+// populateFrom places the mines as if the first move is at the given
+// coordinate, starting from the given number of random draws, and starts the
+// game. It's used by replays to place the same mines as the recorded game
+func (g *FiniteGame) populateFrom(x, y int, draws uint64) {
+	if g.state != GameStateStart {
+		return
+	}
+	g.source = newCountingSource(g.source.seed, draws)
+	g.rng = rand.New(g.source)
+	g.populateField(x, y)
+	g.start()
+	if g.history.depth == 0 {
+		g.observers.emit(StateChangedEvent{GameStateStart, g.state})
+	}
+}
+
+// NoGuessErr returns the error from generating a no-guess field (see
+// WithNoGuess), if the first move fell back to a field that might need
+// guessing. It isn't saved, so it's nil for a loaded game
+func (g *FiniteGame) NoGuessErr() error {
+	return g.noGuessErr
+}
+
+func (g *FiniteGame) start() {
+	// Record where the mines were placed from, as generating the field again
+	// can give a different field (the no-guess timeout depends on how fast it
+	// runs)
+	g.replay.populated(g.fieldDraws)
+	// Set the start time
+	g.startTime = time.Now()
+	// Set the game as started
+	g.state = GameStatePlaying
+}
+
 func (g *FiniteGame) Flag(x, y int) float64 {
+	g.record(ActionFlag, x, y)
+
 	// If the x or y is out of range, the cell is already discovered, or the
 	// game has ended
 	if x < 0 || x >= g.w || y < 0 || y >= g.h ||
@@ -149,22 +438,99 @@ func (g *FiniteGame) Flag(x, y int) float64 {
 		return g.RemainingMines()
 	}
 
-	// Invert the flag field
-	g.field[y][x].Flagged = !g.field[y][x].Flagged
+	g.beginMove()
+	defer g.endMove()
 
-	if g.field[y][x].Flagged {
+	// Add another flag, or remove the flags if the tile already has as many
+	// as it can have mines
+	tile := g.modify(x, y)
+	if int(tile.Flags) < g.maxMines {
+		tile.Flags++
 		g.flags++
 	} else {
-		g.flags--
+		g.flags -= int(tile.Flags)
+		tile.Flags = 0
 	}
+	tile.Flagged = tile.Flags > 0
 
 	return g.RemainingMines()
 }
 
+func (g *FiniteGame) Undo() bool {
+	g.record(ActionUndo, 0, 0)
+	m, ok := g.history.undo(g.state)
+	if ok {
+		m.(*finiteMove).undo(g)
+	}
+	return ok
+}
+
+func (g *FiniteGame) Redo() bool {
+	g.record(ActionRedo, 0, 0)
+	m, ok := g.history.redo()
+	if ok {
+		m.(*finiteMove).redo(g)
+	}
+	return ok
+}
+
+func (g *FiniteGame) Subscribe(f func(Event)) (unsubscribe func()) {
+	return g.observers.subscribe(f)
+}
+
+// Replay returns a copy of the recording of the moves made in the game
+func (g *FiniteGame) Replay() *Replay {
+	return g.replay.copy()
+}
+
+// record adds an action to the game's replay, unless the action is part of
+// another move
+func (g *FiniteGame) record(t ActionType, x, y int) {
+	if g.history.depth == 0 {
+		g.replay.record(t, x, y)
+	}
+}
+
+// startRecording starts a new replay of the game from its current state
+func (g *FiniteGame) startRecording() error {
+	// Save the game without a replay
+	g.replay = nil
+	var buf bytes.Buffer
+	err := g.Save(&buf)
+	if err != nil {
+		return err
+	}
+	g.replay = newReplay(buf.Bytes())
+	return nil
+}
+
+func (g *FiniteGame) Clone() Game {
+	c := *g
+	c.field = make(Field, len(g.field))
+	for y, row := range g.field {
+		c.field[y] = append([]Tile(nil), row...)
+	}
+	c.source = g.source.clone()
+	c.rng = rand.New(c.source)
+	c.history = g.history.clone()
+	c.replay = g.replay.copy()
+	// The subscribers are for the original game
+	c.observers = observers{}
+	return &c
+}
+
 func (g *FiniteGame) State() GameState {
 	return g.state
 }
 
+func (g *FiniteGame) FirstMovePolicy() (FirstMovePolicy, int) {
+	return g.firstMovePolicy.policy, g.firstMovePolicy.minOpening
+}
+
+func (g *FiniteGame) Lives() int {
+	return g.lives
+}
+
 func (g *FiniteGame) StartTime() time.Time {
 	return g.startTime
 }
@@ -180,12 +546,53 @@ func (g *FiniteGame) Size() (w, h int) {
 	return g.w, g.h
 }
 
+// Toroidal returns whether the edges of the field wrap around, see
+// WithToroidal
+func (g *FiniteGame) Toroidal() bool {
+	return g.topology.wrap
+}
+
+// Neighbours returns the positions of the tiles next to the given coordinate
+func (g *FiniteGame) Neighbours(x, y int) []Pos {
+	return g.topology.neighbours(Pos{x, y}, g.w, g.h)
+}
+
 func (g *FiniteGame) StartingMines() int {
 	return g.numMines
 }
 
+// MaxMines returns the most mines a tile can have, which is only more than 1
+// in multimine games, see WithMultiMines
+func (g *FiniteGame) MaxMines() int {
+	return g.maxMines
+}
+
+// FlagCount returns the number of flags on the tile at the given coordinate
+func (g *FiniteGame) FlagCount(x, y int) int {
+	if x < 0 || x >= g.w || y < 0 || y >= g.h {
+		return 0
+	}
+	return int(g.field[y][x].Flags)
+}
+
+// MineCount returns the number of mines on the tile at the given coordinate,
+// if the tile is a discovered mine
+func (g *FiniteGame) MineCount(x, y int) int {
+	if x < 0 || x >= g.w || y < 0 || y >= g.h ||
+		g.tileAppearance(x, y) != TileTypeMine {
+		return 0
+	}
+	return int(g.field[y][x].Mines)
+}
+
+// Seed returns the seed the game's mines are generated from
+func (g *FiniteGame) Seed() int64 {
+	return g.source.seed
+}
+
 func (g *FiniteGame) RemainingMines() float64 {
-	return float64(g.numMines - g.flags)
+	// The mines that have exploded have been found
+	return float64(g.numMines - g.flags - g.exploded)
 }
 
 func (g *FiniteGame) Appearance(x, y, w, h int) map[Pos]TileType {
@@ -198,34 +605,41 @@ func (g *FiniteGame) Appearance(x, y, w, h int) map[Pos]TileType {
 	appearance := make(map[Pos]TileType, w*h)
 	for y := y; y < maxY; y++ {
 		for x := x; x < maxX; x++ {
-			pos := Pos{x, y}
-			// If the game hasn't started yet, or the tile is undiscovered
-			if g.state == GameStateStart || !g.field[y][x].Discovered {
-				// If the tile is flagged
-				if g.field[y][x].Flagged {
-					// Set the tile as flagged
-					appearance[pos] = TileTypeFlag
-				} else {
-					// Set the tile as hidden
-					appearance[pos] = TileTypeHidden
-				}
-			} else {
-				appearance[pos] = g.field[y][x].Type
-			}
+			appearance[Pos{x, y}] = g.tileAppearance(x, y)
 		}
 	}
 	return appearance
 }
 
+// tileAppearance returns the appearance of a single tile, see Appearance
+func (g *FiniteGame) tileAppearance(x, y int) TileType {
+	// If the game hasn't started yet, or the tile is undiscovered
+	if g.state == GameStateStart || !g.field[y][x].Discovered {
+		// If the tile is flagged
+		if g.field[y][x].Flagged {
+			return TileTypeFlag
+		}
+		return TileTypeHidden
+	}
+	if g.field[y][x].Exploded {
+		return TileTypeExploded
+	}
+	return g.field[y][x].Type
+}
+
 func (g *FiniteGame) Save(w io.Writer) error {
-	err := newHeader(saveHeaderFiniteGameType).save(w)
+	h := g.topology.header()
+	g.firstMovePolicy.setHeader(&h)
+	err := h.save(w)
 	if err != nil {
 		return err
 	}
 
-	// Write the field size, number of mines and start time as 64 bit ints
+	// Write the field size, number of mines, start time, seed and the number
+	// of random draws as 64 bit ints
 	for _, data := range []int64{int64(g.w), int64(g.h),
-		int64(g.numMines), g.startTime.UnixNano()} {
+		int64(g.numMines), g.startTime.UnixNano(),
+		g.source.seed, int64(g.source.draws)} {
 		err = binary.Write(w, serialiseByteOrder, data)
 		if err != nil {
 			return err
@@ -238,6 +652,31 @@ func (g *FiniteGame) Save(w io.Writer) error {
 		return err
 	}
 
+	// Write the no-guess options
+	err = g.noGuess.save(w)
+	if err != nil {
+		return err
+	}
+
+	// Write the undo policy
+	err = binary.Write(w, serialiseByteOrder, g.history.policy)
+	if err != nil {
+		return err
+	}
+
+	// Write the neighbourhood, the number of layers, the most mines a tile
+	// can have, and the lives
+	err = saveNeighbourhood(w, g.topology.neighbourhood)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(w, serialiseByteOrder,
+		[]int64{int64(g.topology.depth), int64(g.maxMines),
+			int64(g.startingLives), int64(g.lives)})
+	if err != nil {
+		return err
+	}
+
 	// Convert the field to bytes and write it
 	_, err = w.Write(g.field.toBytes())
 	if err != nil {
@@ -246,15 +685,29 @@ func (g *FiniteGame) Save(w io.Writer) error {
 
 	// no need to save g.flags, it can be calculated from g.field
 
-	return nil
+	// Write the replay
+	return saveGameReplay(w, g.replay)
+}
+
+func loadFinite(r io.Reader, h saveHeader) (Game, error) {
+	g, err := loadFiniteGame(r, h)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
 }
 
-func loadFinite(r io.Reader) (Game, error) {
-	g := &FiniteGame{}
+func loadFiniteGame(r io.Reader, h saveHeader) (*FiniteGame, error) {
+	g := &FiniteGame{topology: topologyFromHeader(h)}
+	var err error
+	g.firstMovePolicy, err = firstMoveFromHeader(h)
+	if err != nil {
+		return nil, err
+	}
 
-	// Read the first 4 fields as 64 bit ints
-	fields := make([]int64, 4)
-	err := binary.Read(r, serialiseByteOrder, fields)
+	// Read the first 6 fields as 64 bit ints
+	fields := make([]int64, 6)
+	err = binary.Read(r, serialiseByteOrder, fields)
 	if err != nil {
 		return nil, err
 	}
@@ -262,8 +715,15 @@ func loadFinite(r io.Reader) (Game, error) {
 	// todo int overflow
 	g.w, g.h = int(fields[0]), int(fields[1])
 	g.numMines = int(fields[2])
+	if g.numMines < 0 {
+		return nil, fmt.Errorf("invalid number of mines %d", g.numMines)
+	}
 	g.startTime = time.Unix(0, fields[3])
 
+	// Restore the random number generator
+	g.source = newCountingSource(fields[4], uint64(fields[5]))
+	g.rng = rand.New(g.source)
+
 	// Read the state byte
 	// todo handle invalid state
 	var state byte
@@ -273,24 +733,59 @@ func loadFinite(r io.Reader) (Game, error) {
 	}
 	g.state = GameState(state)
 
+	// Read the no-guess options
+	g.noGuess, err = loadNoGuessOptions(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the undo policy
+	err = binary.Read(r, serialiseByteOrder, &g.history.policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the neighbourhood, the number of layers, the most mines a tile can
+	// have, and the lives
+	g.topology.neighbourhood, err = loadNeighbourhood(r)
+	if err != nil {
+		return nil, err
+	}
+	fields = make([]int64, 4)
+	err = binary.Read(r, serialiseByteOrder, fields)
+	if err != nil {
+		return nil, err
+	}
+	// todo int overflow
+	g.topology.depth = int(fields[0])
+	g.maxMines = int(fields[1])
+	g.startingLives, g.lives = int(fields[2]), int(fields[3])
+
 	// Read the field bytes
-	fieldBytes := make([]byte, g.w*g.h)
-	_, err = r.Read(fieldBytes)
+	fieldBytes := make([]byte, g.w*g.h*tileSize)
+	_, err = io.ReadFull(r, fieldBytes)
 	if err != nil {
 		return nil, err
 	}
 	// Convert
 	g.field = fieldFromBytes(g.w, g.h, fieldBytes)
 
-	// Calculate the number of flags
+	// Calculate the number of flags, and the mines that have exploded
 	for _, row := range g.field {
 		for _, tile := range row {
-			if tile.Flagged {
-				g.flags++
+			g.flags += int(tile.Flags)
+			if tile.Exploded {
+				g.exploded += int(tile.Mines)
 			}
 		}
 	}
 
+	// Read the replay
+	g.replay, err = loadGameReplay(r)
+	if err != nil {
+		return nil, err
+	}
+
 	return g, nil
 }
 
@@ -300,75 +795,198 @@ type tileAndPos struct {
 }
 
 func (g *FiniteGame) neighbouringTiles(x, y int) []tileAndPos {
+	neighbours := g.Neighbours(x, y)
 	// The array of neighbouring tiles
-	tiles := make([]tileAndPos, 0, 8)
-	// Iterate over the 3x3 around the tile
-	for neighbourY := y - 1; neighbourY <= y+1; neighbourY++ {
-		for neighbourX := x - 1; neighbourX <= x+1; neighbourX++ {
-			// Skip the tile if it's not on the grid or if the tile is the
-			// center tile
-			if neighbourY < 0 || neighbourX < 0 ||
-				neighbourY >= g.h || neighbourX >= g.w ||
-				(neighbourY == y && neighbourX == x) {
-				continue
-			}
-			// Add the tile
-			tiles = append(tiles, tileAndPos{Pos{X: neighbourX, Y: neighbourY},
-				g.field[neighbourY][neighbourX]})
-		}
+	tiles := make([]tileAndPos, len(neighbours))
+	for i, pos := range neighbours {
+		tiles[i] = tileAndPos{pos, g.field[pos.Y][pos.X]}
 	}
 	return tiles
 }
 
-func (g *FiniteGame) neighbouringMinesCount(x, y int) int {
-	// Get the neighbouring tiles
-	neighbouringTiles := g.neighbouringTiles(x, y)
-	// Count the number of mines
-	count := 0
-	for _, tile := range neighbouringTiles {
-		if tile.Type == TileTypeMine {
-			count++
+func (g *FiniteGame) populateField(startX, startY int) {
+	g.fieldDraws = g.source.draws
+
+	// Clear the field from any previous game, but keep the flags
+	for _, row := range g.field {
+		for x := range row {
+			row[x] = Tile{Flagged: row[x].Flagged, Flags: row[x].Flags}
 		}
 	}
-	return count
-}
 
-func (g *FiniteGame) populateField(startX, startY int) {
+	// The tiles kept free of mines for the first move can't have mines
+	var safe []int
+	neighbours := func(p Pos) []Pos {
+		return g.Neighbours(p.X, p.Y)
+	}
+	for _, pos := range g.firstMovePolicy.safeArea(Pos{startX, startY},
+		neighbours, g.rng) {
+		safe = append(safe, pos.Y*g.w+pos.X)
+	}
+	sort.Ints(safe)
+
 	// Place the mines
-	for i := 0; i < g.numMines; i++ {
-		// Loop until the mine is placed
-		for true {
-			// Find a random spot to place the mine
-			y := rand.Intn(g.h)
-			x := rand.Intn(g.w)
-			// If the spot doesn't already have a mine and isn't near the
-			// start point
-			if g.field[y][x].Type != TileTypeMine &&
-				!(x >= startX-1 && x <= startX+1 &&
-					y >= startY-1 && y <= startY+1) {
-				// Set the tile as a mine
-				g.field[y][x].Type = TileTypeMine
-				// We placed a mine, break out of this loop
-				break
+	mines := sampleTiles(g.rng, g.w*g.h, g.maxMines, g.numMines, safe)
+	for _, i := range mines {
+		tile := &g.field[i/g.w][i%g.w]
+		tile.Type = TileTypeMine
+		tile.Mines++
+	}
+
+	// Determine the neighbouring mines. If there are fewer mines than safe
+	// tiles, it's quicker to add each mine to its neighbours. That's only the
+	// same when the mine is in their neighbourhood too, so it isn't done for
+	// neighbourhoods that aren't symmetric
+	var numbers []uint8
+	if len(mines) < g.w*g.h-len(mines) && g.topology.neighbourhood.symmetric() {
+		numbers = make([]uint8, g.w*g.h)
+		for _, i := range mines {
+			for _, pos := range g.Neighbours(i%g.w, i/g.w) {
+				numbers[pos.Y*g.w+pos.X]++
 			}
 		}
 	}
-	// todo more efficient to shuffle (fisher-yates)
-
-	// Determine the neighbouring mines
 	for y, row := range g.field {
-		for x, tile := range row {
-			// Reset the flags
-			tile.Discovered = false
-			tile.Flagged = false
-
+		for x := range row {
 			// Skip tiles that are a mine
-			if tile.Type == TileTypeMine {
+			if row[x].Type == TileTypeMine {
 				continue
 			}
 			// Set the tile's number
-			g.field[y][x].Type = TileType(int(TileTypeEmpty) +
-				g.neighbouringMinesCount(x, y))
+			count := 0
+			if numbers != nil {
+				count = int(numbers[y*g.w+x])
+			} else {
+				for _, pos := range g.Neighbours(x, y) {
+					count += int(g.field[pos.Y][pos.X].Mines)
+				}
+			}
+			row[x].Type = TileTypeNumber(count)
+		}
+	}
+}
+
+// finiteSnapshot is the state of a FiniteGame that moves change, other than
+// the tiles
+type finiteSnapshot struct {
+	state     GameState
+	startTime time.Time
+	flags     int
+	lives     int
+	exploded  int
+}
+
+func (g *FiniteGame) snapshot() finiteSnapshot {
+	return finiteSnapshot{
+		state:     g.state,
+		startTime: g.startTime,
+		flags:     g.flags,
+		lives:     g.lives,
+		exploded:  g.exploded,
+	}
+}
+
+func (g *FiniteGame) restore(s finiteSnapshot) {
+	g.state = s.state
+	g.startTime = s.startTime
+	g.flags = s.flags
+	g.lives = s.lives
+	g.exploded = s.exploded
+}
+
+type finiteTileChange struct {
+	Pos
+	before, after Tile
+}
+
+// finiteMove is a move in a FiniteGame's history. The mines placed by the
+// first move aren't part of the move, so undoing the first move just hides
+// the tiles again, and the game carries on with the same mines
+type finiteMove struct {
+	before, after finiteSnapshot
+	changes       []finiteTileChange
+}
+
+func (m *finiteMove) undo(g *FiniteGame) {
+	for _, c := range m.changes {
+		g.field[c.Y][c.X] = c.before
+	}
+	g.restore(m.before)
+	g.emitChanges(m.changes, true, m.after.state)
+}
+
+func (m *finiteMove) redo(g *FiniteGame) {
+	for _, c := range m.changes {
+		g.field[c.Y][c.X] = c.after
+	}
+	g.restore(m.after)
+	g.emitChanges(m.changes, false, m.before.state)
+}
+
+// emitChanges emits the events for the given tile changes (or for undoing
+// them), and the change from the given state
+func (g *FiniteGame) emitChanges(changes []finiteTileChange, undo bool,
+	before GameState) {
+	if g.observers.active() {
+		for _, c := range changes {
+			from, to := c.before, c.after
+			if undo {
+				from, to = to, from
+			}
+			g.events.tile(c.Pos, from.Discovered, to.Discovered,
+				int(from.Flags), int(to.Flags), g.tileAppearance(c.X, c.Y))
+		}
+	}
+	g.events.emit(&g.observers, before, g.state)
+}
+
+// beginMove starts recording a move for the game's history
+func (g *FiniteGame) beginMove() {
+	if g.history.begin() {
+		g.changed = make(map[Pos]Tile)
+		g.moveBefore = g.snapshot()
+	}
+}
+
+// endMove stops recording a move, and adds it to the game's history if
+// anything changed
+func (g *FiniteGame) endMove() {
+	if !g.history.end() {
+		return
+	}
+
+	m := &finiteMove{
+		before: g.moveBefore,
+		after:  g.snapshot(),
+	}
+	for pos, before := range g.changed {
+		after := g.field[pos.Y][pos.X]
+		if before != after {
+			m.changes = append(m.changes, finiteTileChange{pos, before, after})
 		}
 	}
+	g.changed = nil
+
+	// The field stays populated if the first move is undone, otherwise the
+	// next move would place the mines again and undo would be a free re-roll.
+	// So undoing it goes back to the game being played, not to the start
+	before := m.before.state
+	if before == GameStateStart {
+		m.before.state = GameStatePlaying
+		m.before.startTime = m.after.startTime
+	}
+
+	if len(m.changes) > 0 || m.before != m.after {
+		g.history.push(m)
+	}
+	g.emitChanges(m.changes, false, before)
+}
+
+// modify returns the tile at the given coordinate so it can be changed,
+// remembering what it was before for the move's history
+func (g *FiniteGame) modify(x, y int) *Tile {
+	if _, ok := g.changed[Pos{x, y}]; !ok && g.changed != nil {
+		g.changed[Pos{x, y}] = g.field[y][x]
+	}
+	return &g.field[y][x]
 }
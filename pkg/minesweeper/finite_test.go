@@ -33,6 +33,336 @@ func TestFiniteSerialiseRoundtrip(t *testing.T) {
 		a.Equal(expected.state, actual.state)
 		a.Equal(expected.field, actual.field)
 		a.Equal(expected.flags, actual.flags)
+		a.Equal(expected.source, actual.source)
 	}
 
 }
+
+func TestFiniteSeed(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewGame(16, 16, 40, WithSeed(seed))
+		a.NoError(err)
+		a.Equal(seed, game.(*FiniteGame).Seed())
+
+		// Save the game before the field is populated
+		var buf bytes.Buffer
+		a.NoError(game.Save(&buf))
+
+		sameSeedGame, err := NewGame(16, 16, 40, WithSeed(seed))
+		a.NoError(err)
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+
+		game.Uncover(8, 8)
+		sameSeedGame.Uncover(8, 8)
+		loadedGame.Uncover(8, 8)
+
+		expected := game.(*FiniteGame).field
+		a.Equal(expected, sameSeedGame.(*FiniteGame).field)
+		a.Equal(expected, loadedGame.(*FiniteGame).field)
+	}
+}
+
+// findChordable finds a discovered number in the game, along with its
+// neighbouring mines and the undiscovered tiles that aren't mines
+func findChordable(g *FiniteGame) (pos Pos, mines, safe []Pos, found bool) {
+	for y, row := range g.field {
+		for x, tile := range row {
+			if !tile.Discovered || tile.Type == TileTypeEmpty {
+				continue
+			}
+			mines, safe = nil, nil
+			for _, neighbour := range g.neighbouringTiles(x, y) {
+				if neighbour.Type == TileTypeMine {
+					mines = append(mines, neighbour.Pos)
+				} else if !neighbour.Discovered {
+					safe = append(safe, neighbour.Pos)
+				}
+			}
+			if len(safe) > 0 {
+				return Pos{x, y}, mines, safe, true
+			}
+		}
+	}
+	return
+}
+
+func TestFiniteNegativeMines(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := NewGame(9, 9, -1)
+	a.Error(err)
+
+	// Resetting with a negative number of mines leaves the game as it was
+	game, err := NewGame(9, 9, 10, WithSeed(1))
+	a.NoError(err)
+	a.Error(game.Reset(-5))
+	a.Equal(float64(10), game.RemainingMines())
+	a.NotPanics(func() {
+		a.Equal(GameStatePlaying, game.Uncover(4, 4))
+	})
+}
+
+func TestFiniteChord(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewGame(16, 16, 40, WithSeed(seed))
+		a.NoError(err)
+		g := game.(*FiniteGame)
+		g.Uncover(8, 8)
+
+		pos, mines, safe, found := findChordable(g)
+		if !found {
+			continue
+		}
+
+		// Chording without the flags does nothing
+		a.Equal(GameStatePlaying, g.Chord(pos.X, pos.Y))
+		a.False(g.field[safe[0].Y][safe[0].X].Discovered)
+
+		// Flagging a safe tile instead of a mine loses the game
+		wrongGame, err := NewGame(16, 16, 40, WithSeed(seed))
+		a.NoError(err)
+		wrong := wrongGame.(*FiniteGame)
+		wrong.Uncover(8, 8)
+		wrong.Flag(safe[0].X, safe[0].Y)
+		for _, mine := range mines[1:] {
+			wrong.Flag(mine.X, mine.Y)
+		}
+		a.Equal(GameStateLoss, wrong.Chord(pos.X, pos.Y))
+
+		// Flagging the mines uncovers the rest
+		for _, mine := range mines {
+			g.Flag(mine.X, mine.Y)
+		}
+		a.NotEqual(GameStateLoss, g.Chord(pos.X, pos.Y))
+		for _, p := range safe {
+			a.True(g.field[p.Y][p.X].Discovered)
+		}
+	}
+}
+
+func TestFiniteClone(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame(16, 16, 40, WithSeed(1))
+	a.NoError(err)
+	game.Uncover(8, 8)
+	game.Flag(0, 0)
+
+	clone := game.Clone()
+	expected := *game.(*FiniteGame)
+	actual := *clone.(*FiniteGame)
+	a.Equal(expected.field, actual.field)
+	a.Equal(expected.state, actual.state)
+	a.Equal(expected.startTime, actual.startTime)
+	a.Equal(expected.flags, actual.flags)
+	a.Equal(expected.source, actual.source)
+	a.Equal(game.Replay().Actions(), clone.Replay().Actions())
+
+	// Changing the clone shouldn't change the original
+	appearance := game.Appearance(0, 0, 16, 16)
+	clone.Flag(15, 0)
+	clone.Uncover(0, 15)
+	a.True(clone.Undo())
+	a.True(clone.Undo())
+	a.Equal(appearance, game.Appearance(0, 0, 16, 16))
+	a.Len(game.Replay().Actions(), 2)
+
+	// Both should generate the same field after being reset
+	a.NoError(game.Reset(40))
+	a.NoError(clone.Reset(40))
+	game.Uncover(8, 8)
+	clone.Uncover(8, 8)
+	a.Equal(game.(*FiniteGame).field, clone.(*FiniteGame).field)
+}
+
+func TestFiniteMultiMines(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewGame(16, 16, 120, WithSeed(seed), WithMultiMines(3))
+		a.NoError(err)
+		g := game.(*FiniteGame)
+		g.Uncover(8, 8)
+
+		// All the mines are placed, and no tile has too many
+		mines := 0
+		for _, row := range g.field {
+			for _, tile := range row {
+				a.LessOrEqual(int(tile.Mines), 3)
+				a.Equal(tile.Mines > 0, tile.Type == TileTypeMine)
+				mines += int(tile.Mines)
+			}
+		}
+		a.Equal(120, mines)
+
+		// The numbers are the total number of mines in the neighbours
+		for y, row := range g.field {
+			for x, tile := range row {
+				if tile.Type == TileTypeMine {
+					continue
+				}
+				count := 0
+				for _, pos := range g.Neighbours(x, y) {
+					count += int(g.field[pos.Y][pos.X].Mines)
+				}
+				a.Equal(TileTypeNumber(count), tile.Type)
+			}
+		}
+
+		// The numbers and mines should survive being saved
+		var buf bytes.Buffer
+		a.NoError(g.Save(&buf))
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+		a.Equal(g.field, loadedGame.(*FiniteGame).field)
+		a.Equal(3, loadedGame.(*FiniteGame).MaxMines())
+	}
+}
+
+func TestFiniteMultiMinesFlag(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame(16, 16, 40, WithSeed(1), WithMultiMines(3))
+	a.NoError(err)
+	g := game.(*FiniteGame)
+
+	// A tile can be flagged as many times as it can have mines, then the
+	// flags are removed
+	for flags := 1; flags <= 3; flags++ {
+		a.Equal(float64(40-flags), g.Flag(0, 0))
+		a.Equal(flags, g.FlagCount(0, 0))
+		a.Equal(TileTypeFlag, g.Appearance(0, 0, 1, 1)[Pos{0, 0}])
+	}
+	a.Equal(float64(40), g.Flag(0, 0))
+	a.Equal(0, g.FlagCount(0, 0))
+	a.Equal(TileTypeHidden, g.Appearance(0, 0, 1, 1)[Pos{0, 0}])
+
+	// Undoing restores the flags
+	a.True(g.Undo())
+	a.Equal(3, g.FlagCount(0, 0))
+	a.Equal(float64(37), g.RemainingMines())
+
+	// The flags should survive being saved
+	var buf bytes.Buffer
+	a.NoError(g.Save(&buf))
+	loadedGame, err := Load(&buf)
+	a.NoError(err)
+	a.Equal(3, loadedGame.(*FiniteGame).FlagCount(0, 0))
+	a.Equal(float64(37), loadedGame.RemainingMines())
+}
+
+func TestFiniteMultiMinesChord(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewGame(16, 16, 80, WithSeed(seed), WithMultiMines(2))
+		a.NoError(err)
+		g := game.(*FiniteGame)
+		g.Uncover(8, 8)
+
+		pos, mines, safe, found := findChordable(g)
+		if !found {
+			continue
+		}
+
+		// Flagging each mine once isn't enough if a tile has 2 mines
+		for _, mine := range mines {
+			g.Flag(mine.X, mine.Y)
+		}
+		for _, mine := range mines {
+			if g.field[mine.Y][mine.X].Mines == 2 {
+				a.Equal(GameStatePlaying, g.Chord(pos.X, pos.Y))
+				a.False(g.field[safe[0].Y][safe[0].X].Discovered)
+				g.Flag(mine.X, mine.Y)
+			}
+		}
+
+		// Flagging every mine uncovers the rest
+		a.NotEqual(GameStateLoss, g.Chord(pos.X, pos.Y))
+		for _, p := range safe {
+			a.True(g.field[p.Y][p.X].Discovered)
+		}
+	}
+}
+
+func TestFiniteMultiMinesOptions(t *testing.T) {
+	a := assert.New(t)
+
+	// There's room for more mines than tiles
+	_, err := NewGame(4, 4, 14, WithMultiMines(2))
+	a.NoError(err)
+	_, err = NewGame(4, 4, 15, WithMultiMines(2))
+	a.Error(err)
+
+	_, err = NewGame(16, 16, 40, WithMultiMines(0))
+	a.Error(err)
+	// The numbers wouldn't fit in a tile's type
+	_, err = NewGame(16, 16, 40, WithMultiMines(100))
+	a.Error(err)
+	_, err = NewGame(16, 16, 40, WithMultiMines(2), WithNoGuess(0, 0))
+	a.Error(err)
+	_, err = NewInfiniteGame(40, WithMultiMines(2))
+	a.Error(err)
+}
+
+func TestFinitePopulateUniform(t *testing.T) {
+	a := assert.New(t)
+
+	// Every tile away from the first move should be a mine as often as the
+	// others
+	const samples = 5000
+	counts := make([]int, 8*8)
+	for seed := int64(0); seed < samples; seed++ {
+		game, err := NewGame(8, 8, 10, WithSeed(seed))
+		a.NoError(err)
+		game.Uncover(0, 0)
+		for y, row := range game.(*FiniteGame).field {
+			for x, tile := range row {
+				counts[y*8+x] += int(tile.Mines)
+			}
+		}
+	}
+
+	var candidates []int
+	for i, count := range counts {
+		if x, y := i%8, i/8; x <= 1 && y <= 1 {
+			a.Zero(count)
+		} else {
+			candidates = append(candidates, count)
+		}
+	}
+	// 102.7 is the critical value for 59 degrees of freedom at p = 0.001
+	expected := float64(samples*10) / float64(len(candidates))
+	a.Less(chiSquared(candidates, expected), 102.7)
+}
+
+func BenchmarkPopulateFieldDense(b *testing.B) {
+	game, err := NewGame(16, 16, 16*16-9, WithSeed(1))
+	if err != nil {
+		b.Fatal(err)
+	}
+	g := game.(*FiniteGame)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.populateField(8, 8)
+	}
+}
+
+func BenchmarkPopulateFieldLarge(b *testing.B) {
+	game, err := NewGame(1000, 1000, 200000, WithSeed(1))
+	if err != nil {
+		b.Fatal(err)
+	}
+	g := game.(*FiniteGame)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.populateField(500, 500)
+	}
+}
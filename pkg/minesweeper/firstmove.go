@@ -0,0 +1,158 @@
+package minesweeper
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// FirstMovePolicy controls which tiles are kept free of mines for the first
+// move, so it doesn't lose the game straight away
+type FirstMovePolicy uint8
+
+const (
+	// FirstMoveSafeOpening keeps mines off the first move and its
+	// neighbours, so the first move always opens an area
+	FirstMoveSafeOpening = FirstMovePolicy(iota)
+
+	// FirstMoveUnprotected places the mines without looking at the first
+	// move, so it can uncover a mine, like the original Windows game
+	FirstMoveUnprotected
+
+	// FirstMoveSafeTile keeps mines off the first move, but not its
+	// neighbours, so it can uncover a number
+	FirstMoveSafeTile
+
+	// FirstMoveMinOpening keeps mines off an area around the first move, so
+	// it opens at least a minimum number of tiles, see WithMinOpening
+	FirstMoveMinOpening
+)
+
+func (p FirstMovePolicy) String() string {
+	switch p {
+	case FirstMoveSafeOpening:
+		return "safe opening"
+	case FirstMoveUnprotected:
+		return "unprotected"
+	case FirstMoveSafeTile:
+		return "safe tile"
+	case FirstMoveMinOpening:
+		return "min opening"
+	default:
+		return "unknown"
+	}
+}
+
+// WithFirstMovePolicy sets which tiles are kept free of mines for the first
+// move, the default is FirstMoveSafeOpening. FirstMoveMinOpening needs the
+// number of tiles to open, so it's set with WithMinOpening instead
+func WithFirstMovePolicy(policy FirstMovePolicy) Option {
+	return func(o *options) {
+		o.firstMove = firstMoveOptions{policy: policy}
+	}
+}
+
+// WithMinOpening makes the first move open at least the given number of
+// tiles (FirstMoveMinOpening). A random area around the first move is kept
+// free of mines, so the field needs room for the opening as well as the mines
+func WithMinOpening(tiles int) Option {
+	return func(o *options) {
+		o.firstMove = firstMoveOptions{policy: FirstMoveMinOpening,
+			minOpening: tiles}
+	}
+}
+
+// firstMoveOptions stores a game's first move policy, see WithFirstMovePolicy
+type firstMoveOptions struct {
+	policy FirstMovePolicy
+
+	// The fewest tiles the first move opens, for FirstMoveMinOpening
+	minOpening int
+}
+
+func (o firstMoveOptions) validate() error {
+	switch o.policy {
+	case FirstMoveSafeOpening, FirstMoveUnprotected, FirstMoveSafeTile:
+		return nil
+	case FirstMoveMinOpening:
+		if o.minOpening < 1 || o.minOpening > math.MaxInt32 {
+			return fmt.Errorf("invalid minimum opening %d", o.minOpening)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown first move policy %d", o.policy)
+	}
+}
+
+// safeTiles returns the most tiles that are kept free of mines for the first
+// move, where each tile has up to maxNeighbours neighbours
+func (o firstMoveOptions) safeTiles(maxNeighbours int) int {
+	switch o.policy {
+	case FirstMoveUnprotected:
+		return 0
+	case FirstMoveSafeTile:
+		return 1
+	case FirstMoveMinOpening:
+		// The area grows by up to maxNeighbours tiles at a time, until it has
+		// at least minOpening tiles
+		return max(o.minOpening-1, 1) + maxNeighbours
+	default:
+		return 1 + maxNeighbours
+	}
+}
+
+// safeArea returns the tiles that can't have mines when the first move is at
+// the given position, where neighbours returns the tiles next to a tile. The
+// shape of a minimum opening is picked with the given random number generator
+func (o firstMoveOptions) safeArea(start Pos, neighbours func(Pos) []Pos,
+	rng *rand.Rand) []Pos {
+	switch o.policy {
+	case FirstMoveUnprotected:
+		return nil
+	case FirstMoveSafeTile:
+		return []Pos{start}
+	}
+
+	// Without mines next to it, the first move opens its neighbours
+	area := append([]Pos{start}, neighbours(start)...)
+	if o.policy != FirstMoveMinOpening {
+		return area
+	}
+
+	// The opening carries on through any of those without mines next to them
+	// too, so a random tile at the edge of the area has its neighbours added
+	// to the area until it's big enough. The field can run out of tiles
+	// first, in which case the whole field is opened
+	inArea := make(map[Pos]bool)
+	for _, p := range area {
+		inArea[p] = true
+	}
+	edge := append([]Pos(nil), area[1:]...)
+	for len(area) < o.minOpening && len(edge) > 0 {
+		i := rng.Intn(len(edge))
+		p := edge[i]
+		edge[i] = edge[len(edge)-1]
+		edge = edge[:len(edge)-1]
+		for _, n := range neighbours(p) {
+			if !inArea[n] {
+				inArea[n] = true
+				area = append(area, n)
+				edge = append(edge, n)
+			}
+		}
+	}
+	return area
+}
+
+// setHeader stores the policy in the given save header
+func (o firstMoveOptions) setHeader(h *saveHeader) {
+	h.FirstMove = uint8(o.policy)
+	h.MinOpening = uint32(o.minOpening)
+}
+
+// firstMoveFromHeader returns the first move policy stored in the given save
+// header
+func firstMoveFromHeader(h saveHeader) (firstMoveOptions, error) {
+	o := firstMoveOptions{FirstMovePolicy(h.FirstMove), int(h.MinOpening)}
+	return o, o.validate()
+}
@@ -0,0 +1,216 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+// countOpened returns the number of discovered tiles in the given area
+func countOpened(game Game, x, y, w, h int) int {
+	opened := 0
+	for _, tileType := range game.Appearance(x, y, w, h) {
+		if tileType != TileTypeHidden && tileType != TileTypeFlag {
+			opened++
+		}
+	}
+	return opened
+}
+
+func TestFirstMoveSafeArea(t *testing.T) {
+	a := assert.New(t)
+
+	neighbours := func(p Pos) []Pos {
+		return (&InfiniteGame{}).Neighbours(p.X, p.Y)
+	}
+	rng := rand.New(rand.NewSource(1))
+	for minOpening := 1; minOpening < 200; minOpening += 7 {
+		o := firstMoveOptions{FirstMoveMinOpening, minOpening}
+		area := o.safeArea(Pos{3, -5}, neighbours, rng)
+		a.GreaterOrEqual(len(area), minOpening)
+		a.LessOrEqual(len(area), o.safeTiles(8))
+		a.Equal(Pos{3, -5}, area[0])
+
+		// Every tile is only in the area once
+		seen := make(map[Pos]bool)
+		for _, p := range area {
+			a.False(seen[p])
+			seen[p] = true
+		}
+	}
+
+	a.Empty(firstMoveOptions{policy: FirstMoveUnprotected}.safeArea(
+		Pos{}, neighbours, rng))
+	a.Equal([]Pos{{}}, firstMoveOptions{policy: FirstMoveSafeTile}.safeArea(
+		Pos{}, neighbours, rng))
+	a.Len(firstMoveOptions{}.safeArea(Pos{}, neighbours, rng), 9)
+}
+
+func TestFiniteFirstMovePolicy(t *testing.T) {
+	a := assert.New(t)
+
+	lost, numbers := 0, 0
+	for seed := int64(0); seed < 20; seed++ {
+		// Without protection the first move can be a mine
+		game, err := NewGame(9, 9, 70, WithSeed(seed),
+			WithFirstMovePolicy(FirstMoveUnprotected))
+		a.NoError(err)
+		if game.Uncover(4, 4) == GameStateLoss {
+			lost++
+		}
+
+		// A safe tile can still be next to mines
+		game, err = NewGame(9, 9, 70, WithSeed(seed),
+			WithFirstMovePolicy(FirstMoveSafeTile))
+		a.NoError(err)
+		a.Equal(GameStatePlaying, game.Uncover(4, 4))
+		if _, ok := game.Appearance(4, 4, 1, 1)[Pos{4, 4}].Number(); ok {
+			numbers++
+		}
+
+		// But by default the first move is an opening
+		game, err = NewGame(9, 9, 70, WithSeed(seed))
+		a.NoError(err)
+		a.NotEqual(GameStateLoss, game.Uncover(4, 4))
+		a.Equal(TileTypeEmpty, game.Appearance(4, 4, 1, 1)[Pos{4, 4}])
+
+		// Which can be made bigger
+		game, err = NewGame(9, 9, 40, WithSeed(seed), WithMinOpening(30))
+		a.NoError(err)
+		a.NotEqual(GameStateLoss, game.Uncover(int(seed%9), int(seed/9)))
+		a.GreaterOrEqual(countOpened(game, 0, 0, 9, 9), 30)
+	}
+	a.Greater(lost, 0)
+	a.Greater(numbers, 0)
+
+	// The opening can be the whole field
+	game, err := NewGame(9, 9, 0, WithMinOpening(100))
+	a.NoError(err)
+	a.Equal(GameStateWin, game.Uncover(0, 0))
+	_, err = NewGame(9, 9, 1, WithMinOpening(100))
+	a.Error(err)
+
+	// The first move policy is saved
+	game, err = NewHexGame(9, 9, 20, WithMinOpening(30))
+	a.NoError(err)
+	var buf bytes.Buffer
+	a.NoError(game.Save(&buf))
+	loadedGame, err := Load(&buf)
+	a.NoError(err)
+	policy, minOpening := loadedGame.FirstMovePolicy()
+	a.Equal(FirstMoveMinOpening, policy)
+	a.Equal(30, minOpening)
+	a.GreaterOrEqual(loadedGame.Uncover(4, 4), GameStatePlaying)
+	a.GreaterOrEqual(countOpened(loadedGame, 0, 0, 9, 9), 30)
+}
+
+func TestFiniteFirstMoveOutOfRange(t *testing.T) {
+	a := assert.New(t)
+
+	for _, option := range []Option{
+		WithFirstMovePolicy(FirstMoveUnprotected),
+		WithFirstMovePolicy(FirstMoveSafeTile),
+		WithFirstMovePolicy(FirstMoveSafeOpening),
+		WithMinOpening(30),
+	} {
+		game, err := NewGame(9, 9, 40, WithSeed(1), option)
+		a.NoError(err)
+		g := game.(*FiniteGame)
+
+		// A move off the field doesn't place the mines
+		for _, pos := range []Pos{{-1, 0}, {9, 4}, {4, -1}, {4, 9}, {-5, -5}} {
+			a.Error(g.Populate(pos.X, pos.Y))
+			a.Equal(GameStateStart, g.Uncover(pos.X, pos.Y))
+			a.Equal(GameStateStart, g.State())
+		}
+		for _, tileType := range g.Appearance(0, 0, 9, 9) {
+			a.Equal(TileTypeHidden, tileType)
+		}
+
+		// So the first move on the field is still protected
+		a.NoError(g.Populate(4, 4))
+		a.Equal(GameStatePlaying, g.State())
+	}
+}
+
+func TestInfiniteFirstMovePolicy(t *testing.T) {
+	a := assert.New(t)
+
+	exploded := 0
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewInfiniteGame(240, WithSeed(seed),
+			WithFirstMovePolicy(FirstMoveUnprotected))
+		a.NoError(err)
+		game.Uncover(3, 3)
+		if game.Appearance(3, 3, 1, 1)[Pos{3, 3}] == TileTypeExploded {
+			exploded++
+		}
+
+		// The opening isn't cut off at the edge of the chunk, even if the
+		// chunks around it already exist
+		game, err = NewInfiniteGame(200, WithSeed(seed))
+		a.NoError(err)
+		game.Flag(15, 15)
+		game.Flag(16, 16)
+		game.Flag(16, 16)
+		a.Equal(GameStatePlaying, game.Uncover(15, 16))
+		a.Equal(TileTypeEmpty, game.Appearance(15, 16, 1, 1)[Pos{15, 16}])
+
+		game, err = NewInfiniteGame(100, WithSeed(seed), WithMinOpening(100))
+		a.NoError(err)
+		a.Equal(GameStatePlaying, game.Uncover(-1, 0))
+		a.GreaterOrEqual(countOpened(game, -128, -128, 256, 256), 100)
+
+		// The same tiles are kept free of mines when the game is loaded,
+		// including in the chunks that weren't saved
+		var buf bytes.Buffer
+		a.NoError(game.Save(&buf))
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+		policy, minOpening := loadedGame.FirstMovePolicy()
+		a.Equal(FirstMoveMinOpening, policy)
+		a.Equal(100, minOpening)
+		for _, g := range []Game{game, loadedGame} {
+			for x := -40; x < 40; x += 3 {
+				g.Uncover(x, x/2)
+			}
+		}
+		a.Equal(game.Appearance(-128, -128, 256, 256),
+			loadedGame.Appearance(-128, -128, 256, 256))
+	}
+	a.Greater(exploded, 0)
+
+	_, err := NewInfiniteGame(1, WithMinOpening(chunkTiles))
+	a.Error(err)
+}
+
+func TestFirstMovePolicyOptions(t *testing.T) {
+	a := assert.New(t)
+
+	for _, opt := range []Option{
+		WithFirstMovePolicy(FirstMoveMinOpening),
+		WithFirstMovePolicy(FirstMovePolicy(100)),
+		WithMinOpening(0),
+	} {
+		_, err := NewGame(9, 9, 10, opt)
+		a.Error(err)
+		_, err = NewInfiniteGame(10, opt)
+		a.Error(err)
+	}
+
+	// The solver needs the first move to be safe
+	_, err := NewGame(9, 9, 10, WithNoGuess(0, 0),
+		WithFirstMovePolicy(FirstMoveUnprotected))
+	a.Error(err)
+	_, err = NewGame(9, 9, 10, WithNoGuess(0, 0), WithMinOpening(20))
+	a.NoError(err)
+
+	// The default is a safe opening
+	game, err := NewGame(9, 9, 10)
+	a.NoError(err)
+	policy, minOpening := game.FirstMovePolicy()
+	a.Equal(FirstMoveSafeOpening, policy)
+	a.Equal(0, minOpening)
+	a.Equal("safe opening", policy.String())
+}
@@ -67,10 +67,24 @@ type Game interface {
 	// state of the game after the move
 	Uncover(x, y int) (s GameState)
 
+	// Chord uncovers every unflagged neighbour of the discovered number at the
+	// given coordinate, if the number of neighbouring flags matches the
+	// number. If any of the flags are wrong, this uncovers a mine. Returns the
+	// state of the game after the move
+	Chord(x, y int) GameState
+
 	// Flag the tile at the given coordinate. If the tile is already flagged it is
 	// unflagged. Returns the number of "remaining mines", see RemainingMines
 	Flag(x, y int) float64
 
+	// Undo the last move (or chord). Returns false if there are no moves to
+	// undo, or the game's UndoPolicy doesn't allow it
+	Undo() bool
+
+	// Redo the last undone move. Returns false if there are no moves to redo.
+	// Making a move forgets the undone moves
+	Redo() bool
+
 	// RemainingMines returns the number of "remaining mines" (assuming every flag is perfect,
 	// so this number can be negative) as a floating point number, as the remaining for
 	// infinite minesweeper is math.Inf(1)
@@ -79,6 +93,15 @@ type Game interface {
 	// State returns the game's current state
 	State() GameState
 
+	// FirstMovePolicy returns which tiles were kept free of mines for the
+	// first move, and the fewest tiles it opens for FirstMoveMinOpening
+	FirstMovePolicy() (policy FirstMovePolicy, minOpening int)
+
+	// Lives returns the number of lives left, or UnlimitedLives. Uncovering a
+	// mine costs a life, and the game is lost when there are none left, see
+	// WithLives
+	Lives() int
+
 	// StartTime returns the start time of the game
 	StartTime() time.Time
 
@@ -89,23 +112,50 @@ type Game interface {
 	// TileTypeHidden). This is a map of Pos, as Go slices can't be sparse
 	Appearance(x, y, w, h int) map[Pos]TileType
 
-	// Save the state of the game into the given io.Writer
+	// Subscribe calls f with every Event in the game from now on, in the order
+	// they happen, so the game's appearance can be updated incrementally. The
+	// events caused by a move are emitted once the move has finished. Returns
+	// a function that stops calling f
+	Subscribe(f func(Event)) (unsubscribe func())
+
+	// Replay returns a recording of the moves made since the game was created
+	// or reset, which can be played back
+	Replay() *Replay
+
+	// Clone returns a deep copy of the game, which can be played independently
+	// of the original
+	Clone() Game
+
+	// Save the state of the game (and its replay) into the given io.Writer
 	Save(io.Writer) error
 }
 
 // Assuming that the loader version is the same for finite and infinite loaders
-const serialiseVersion = 1
+const serialiseVersion = 15
 
 var serialiseByteOrder = binary.BigEndian
 
 const (
 	saveHeaderFiniteGameType = uint8(iota)
 	saveHeaderInfiniteGameType
+	saveHeaderHexGameType
+	saveHeaderGame3DType
+)
+
+const (
+	// saveHeaderWrapFlag is set for finite games where the edges of the field
+	// wrap around
+	saveHeaderWrapFlag = uint8(1 << iota)
 )
 
 type saveHeader struct {
 	Version  uint8
 	GameType uint8
+	Flags    uint8
+
+	// The game's first move policy, see firstMoveOptions.setHeader
+	FirstMove  uint8
+	MinOpening uint32
 }
 
 func (h saveHeader) save(w io.Writer) error {
@@ -144,9 +194,13 @@ func Load(r io.Reader) (Game, error) {
 
 	switch header.GameType {
 	case saveHeaderFiniteGameType:
-		return loadFinite(r)
+		return loadFinite(r, header)
 	case saveHeaderInfiniteGameType:
-		return loadInfinite(r)
+		return loadInfinite(r, header)
+	case saveHeaderHexGameType:
+		return loadHex(r, header)
+	case saveHeaderGame3DType:
+		return loadGame3D(r, header)
 	default:
 		return nil, fmt.Errorf("unknown game type")
 	}
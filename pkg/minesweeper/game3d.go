@@ -0,0 +1,149 @@
+package minesweeper
+
+import (
+	"fmt"
+	"io"
+)
+
+// Pos3D is the position of a tile in a Game3D
+type Pos3D struct {
+	X, Y, Z int
+}
+
+// Game3D stores a finite minesweeper game on a grid of cubes, where each tile
+// has the 26 tiles around it as neighbours. The game is a FiniteGame where
+// the layers of the grid are stacked on top of each other, so it can also be
+// used as a Game, where tile (x, y, z) is at (x, y + z*height). The 3D methods
+// convert to and from these positions, and Pos3D converts the positions in
+// the game's Events
+type Game3D struct {
+	*FiniteGame
+
+	// The height of a layer
+	layerHeight int
+}
+
+// NewGame3D creates a new, finite minesweeper game on a grid of cubes
+func NewGame3D(width, height, depth int, numMines int, opts ...Option) (Game, error) {
+	if width <= 0 || height <= 0 || depth <= 0 {
+		return nil, fmt.Errorf("invalid size %d x %d x %d", width, height, depth)
+	}
+	g, err := newFiniteGame(width, height*depth, numMines,
+		topology{tiling: tilingCube, depth: depth}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Game3D{g, height}, nil
+}
+
+// Size3D returns the width, height and depth of the game
+func (g *Game3D) Size3D() (w, h, d int) {
+	return g.w, g.layerHeight, g.topology.depth
+}
+
+// inRange returns whether the given position is in the game
+func (g *Game3D) inRange(p Pos3D) bool {
+	return p.X >= 0 && p.X < g.w && p.Y >= 0 && p.Y < g.layerHeight &&
+		p.Z >= 0 && p.Z < g.topology.depth
+}
+
+// Pos returns where the given position is in the stacked layers. Positions
+// that aren't in the game are converted to (-1, -1), so they aren't in the
+// stacked layers either
+func (g *Game3D) Pos(p Pos3D) Pos {
+	if !g.inRange(p) {
+		return Pos{-1, -1}
+	}
+	return Pos{p.X, p.Y + p.Z*g.layerHeight}
+}
+
+// Pos3D returns the position of a tile in the stacked layers in 3D
+func (g *Game3D) Pos3D(p Pos) Pos3D {
+	return Pos3D{p.X, mod(p.Y, g.layerHeight), p.Y / g.layerHeight}
+}
+
+// Uncover3D uncovers the tile at the given coordinate, see Game.Uncover
+func (g *Game3D) Uncover3D(x, y, z int) GameState {
+	p := g.Pos(Pos3D{x, y, z})
+	return g.Uncover(p.X, p.Y)
+}
+
+// Chord3D uncovers the neighbours of the tile at the given coordinate, see
+// Game.Chord
+func (g *Game3D) Chord3D(x, y, z int) GameState {
+	p := g.Pos(Pos3D{x, y, z})
+	return g.Chord(p.X, p.Y)
+}
+
+// Flag3D flags the tile at the given coordinate, see Game.Flag
+func (g *Game3D) Flag3D(x, y, z int) float64 {
+	p := g.Pos(Pos3D{x, y, z})
+	return g.Flag(p.X, p.Y)
+}
+
+// Populate3D populates the game as if the first move is at the given
+// coordinate, see FiniteGame.Populate
+func (g *Game3D) Populate3D(x, y, z int) error {
+	p := g.Pos(Pos3D{x, y, z})
+	return g.Populate(p.X, p.Y)
+}
+
+// Neighbours3D returns the positions of the tiles next to the given
+// coordinate
+func (g *Game3D) Neighbours3D(x, y, z int) []Pos3D {
+	if !g.inRange(Pos3D{x, y, z}) {
+		return nil
+	}
+	neighbours := g.Neighbours(x, y+z*g.layerHeight)
+	positions := make([]Pos3D, len(neighbours))
+	for i, pos := range neighbours {
+		positions[i] = g.Pos3D(pos)
+	}
+	return positions
+}
+
+// Appearance3D returns the appearance of the tiles in the given cuboid, see
+// Game.Appearance
+func (g *Game3D) Appearance3D(x, y, z, w, h, d int) map[Pos3D]TileType {
+	// Make sure the cuboid is in range
+	x, y, z = max(x, 0), max(y, 0), max(z, 0)
+	maxX := min(x+w, g.w)
+	maxY := min(y+h, g.layerHeight)
+	maxZ := min(z+d, g.topology.depth)
+
+	appearance := make(map[Pos3D]TileType)
+	for z := z; z < maxZ; z++ {
+		for y := y; y < maxY; y++ {
+			for x := x; x < maxX; x++ {
+				appearance[Pos3D{x, y, z}] =
+					g.tileAppearance(x, y+z*g.layerHeight)
+			}
+		}
+	}
+	return appearance
+}
+
+// LayerAppearance returns the appearance of the given rect of a single layer,
+// see Game.Appearance
+func (g *Game3D) LayerAppearance(z, x, y, w, h int) map[Pos]TileType {
+	appearance := make(map[Pos]TileType)
+	for pos, tileType := range g.Appearance3D(x, y, z, w, h, 1) {
+		appearance[Pos{pos.X, pos.Y}] = tileType
+	}
+	return appearance
+}
+
+func (g *Game3D) Clone() Game {
+	return &Game3D{g.FiniteGame.Clone().(*FiniteGame), g.layerHeight}
+}
+
+func loadGame3D(r io.Reader, h saveHeader) (Game, error) {
+	g, err := loadFiniteGame(r, h)
+	if err != nil {
+		return nil, err
+	}
+	if g.topology.depth <= 0 || g.h%g.topology.depth != 0 {
+		return nil, fmt.Errorf("invalid number of layers %d", g.topology.depth)
+	}
+	return &Game3D{g, g.h / g.topology.depth}, nil
+}
@@ -0,0 +1,140 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGame3DNeighbours(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame3D(4, 4, 4, 10)
+	a.NoError(err)
+	g := game.(*Game3D)
+
+	a.Len(g.Neighbours3D(1, 1, 1), 26)
+	a.ElementsMatch([]Pos3D{
+		{1, 0, 0}, {0, 1, 0}, {1, 1, 0},
+		{0, 0, 1}, {1, 0, 1}, {0, 1, 1}, {1, 1, 1},
+	}, g.Neighbours3D(0, 0, 0))
+
+	// The bottom of a layer isn't next to the top of the next layer
+	a.NotContains(g.Neighbours3D(0, 3, 0), Pos3D{0, 0, 1})
+	a.NotContains(g.Neighbours(0, 3), Pos{0, 4})
+
+	// Unless the edges wrap
+	game, err = NewGame3D(4, 4, 4, 10, WithToroidal())
+	a.NoError(err)
+	g = game.(*Game3D)
+	a.Len(g.Neighbours3D(0, 0, 0), 26)
+	a.Contains(g.Neighbours3D(0, 0, 0), Pos3D{3, 3, 3})
+}
+
+func TestGame3DPos(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame3D(4, 5, 6, 10)
+	a.NoError(err)
+	g := game.(*Game3D)
+
+	w, h, d := g.Size3D()
+	a.Equal([]int{4, 5, 6}, []int{w, h, d})
+	a.Equal(Pos{2, 3 + 4*5}, g.Pos(Pos3D{2, 3, 4}))
+	a.Equal(Pos3D{2, 3, 4}, g.Pos3D(Pos{2, 3 + 4*5}))
+
+	// Positions outside the game aren't wrapped into other layers
+	a.Equal(Pos{-1, -1}, g.Pos(Pos3D{0, 5, 0}))
+}
+
+func TestGame3DPopulate(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 20; seed++ {
+		game, err := NewGame3D(8, 8, 8, 60, WithSeed(seed))
+		a.NoError(err)
+		g := game.(*Game3D)
+		g.Uncover3D(4, 4, 4)
+
+		isMine := func(p Pos3D) bool {
+			return g.field[p.Y+p.Z*8][p.X].Type == TileTypeMine
+		}
+
+		// The first move and its neighbours are never mines
+		a.False(isMine(Pos3D{4, 4, 4}))
+		for _, pos := range g.Neighbours3D(4, 4, 4) {
+			a.False(isMine(pos))
+		}
+
+		// So the first move uncovers its neighbours
+		appearance := g.Appearance3D(0, 0, 0, 8, 8, 8)
+		a.Len(appearance, 8*8*8)
+		for _, pos := range g.Neighbours3D(4, 4, 4) {
+			a.NotEqual(TileTypeHidden, appearance[pos])
+		}
+
+		// The numbers count the 26 neighbours
+		for pos, tileType := range appearance {
+			if number, ok := tileType.Number(); ok {
+				count := 0
+				for _, neighbour := range g.Neighbours3D(pos.X, pos.Y, pos.Z) {
+					if isMine(neighbour) {
+						count++
+					}
+				}
+				a.Equal(count, number)
+			}
+		}
+
+		// The layers should match the stacked appearance
+		stacked := g.Appearance(0, 0, 8, 8*8)
+		for z := 0; z < 8; z++ {
+			for pos, tileType := range g.LayerAppearance(z, 0, 0, 8, 8) {
+				a.Equal(stacked[Pos{pos.X, pos.Y + z*8}], tileType)
+			}
+		}
+	}
+}
+
+func TestGame3DTooManyMines(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := NewGame3D(3, 3, 3, 0)
+	a.NoError(err)
+	_, err = NewGame3D(3, 3, 3, 1)
+	a.Error(err)
+	_, err = NewGame3D(3, 3, 0, 0)
+	a.Error(err)
+}
+
+func TestGame3DSerialiseRoundtrip(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame3D(8, 6, 4, 20, WithSeed(1))
+	a.NoError(err)
+	g := game.(*Game3D)
+	g.Uncover3D(2, 2, 2)
+	g.Flag3D(7, 5, 3)
+
+	var buf bytes.Buffer
+	a.NoError(game.Save(&buf))
+	loadedGame, err := Load(&buf)
+	a.NoError(err)
+
+	// The game should still be a 3D game
+	a.IsType(&Game3D{}, loadedGame)
+	loaded := loadedGame.(*Game3D)
+	w, h, d := loaded.Size3D()
+	a.Equal([]int{8, 6, 4}, []int{w, h, d})
+	a.Equal(g.Appearance3D(0, 0, 0, 8, 6, 4), loaded.Appearance3D(0, 0, 0, 8, 6, 4))
+
+	// And so should its replay and clones
+	replayGame, err := loaded.Replay().Game()
+	a.NoError(err)
+	a.IsType(&Game3D{}, replayGame)
+	a.IsType(&Game3D{}, loaded.Clone())
+
+	// Both games should play the same
+	a.Equal(g.Uncover3D(0, 0, 0), loaded.Uncover3D(0, 0, 0))
+	a.Equal(g.Appearance3D(0, 0, 0, 8, 6, 4), loaded.Appearance3D(0, 0, 0, 8, 6, 4))
+}
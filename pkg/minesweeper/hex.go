@@ -0,0 +1,34 @@
+package minesweeper
+
+import "io"
+
+// HexGame stores a finite minesweeper game on a grid of hexagons, where each
+// tile has 6 neighbours. The tiles are in rows, with the odd rows shifted half
+// a tile to the right, so the tile at (x, y) in an odd row is next to (x, y-1)
+// and (x+1, y-1) in the row above it. Otherwise it's played the same as a
+// FiniteGame
+type HexGame struct {
+	*FiniteGame
+}
+
+// NewHexGame creates a new, finite minesweeper game on a grid of hexagons
+func NewHexGame(width int, height int, numMines int, opts ...Option) (Game, error) {
+	g, err := newFiniteGame(width, height, numMines,
+		topology{tiling: tilingHex, depth: 1}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &HexGame{g}, nil
+}
+
+func (g *HexGame) Clone() Game {
+	return &HexGame{g.FiniteGame.Clone().(*FiniteGame)}
+}
+
+func loadHex(r io.Reader, h saveHeader) (Game, error) {
+	g, err := loadFiniteGame(r, h)
+	if err != nil {
+		return nil, err
+	}
+	return &HexGame{g}, nil
+}
@@ -0,0 +1,100 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHexNeighbours(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewHexGame(8, 8, 10)
+	a.NoError(err)
+	g := game.(*HexGame)
+
+	// Even rows are next to the tiles up and left
+	a.ElementsMatch([]Pos{{2, 1}, {3, 1}, {2, 2}, {4, 2}, {2, 3}, {3, 3}},
+		g.Neighbours(3, 2))
+	// Odd rows are next to the tiles up and right
+	a.ElementsMatch([]Pos{{3, 2}, {4, 2}, {2, 3}, {4, 3}, {3, 4}, {4, 4}},
+		g.Neighbours(3, 3))
+	// The edges have fewer neighbours
+	a.ElementsMatch([]Pos{{1, 0}, {0, 1}}, g.Neighbours(0, 0))
+	a.ElementsMatch([]Pos{{6, 7}, {7, 6}}, g.Neighbours(7, 7))
+}
+
+func TestHexPopulate(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 20; seed++ {
+		game, err := NewHexGame(16, 16, 100, WithSeed(seed))
+		a.NoError(err)
+		g := game.(*HexGame)
+		g.Uncover(8, 8)
+
+		// The first move and its neighbours are never mines
+		a.NotEqual(TileTypeMine, g.field[8][8].Type)
+		for _, pos := range g.Neighbours(8, 8) {
+			a.NotEqual(TileTypeMine, g.field[pos.Y][pos.X].Type)
+		}
+
+		// The numbers count the 6 neighbours
+		mines := 0
+		for y, row := range g.field {
+			for x, tile := range row {
+				if tile.Type == TileTypeMine {
+					mines++
+					continue
+				}
+				count := 0
+				for _, pos := range g.Neighbours(x, y) {
+					if g.field[pos.Y][pos.X].Type == TileTypeMine {
+						count++
+					}
+				}
+				a.Equal(TileType(int(TileTypeEmpty)+count), tile.Type)
+				a.LessOrEqual(count, 6)
+			}
+		}
+		a.Equal(100, mines)
+	}
+}
+
+func TestHexTooManyMines(t *testing.T) {
+	a := assert.New(t)
+
+	// Only the first move and its 6 neighbours need to be free
+	_, err := NewHexGame(4, 4, 4*4-7)
+	a.NoError(err)
+	_, err = NewHexGame(4, 4, 4*4-6)
+	a.Error(err)
+}
+
+func TestHexSerialiseRoundtrip(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewHexGame(16, 16, 40, WithSeed(1))
+	a.NoError(err)
+	game.Uncover(8, 8)
+	game.Flag(0, 0)
+
+	var buf bytes.Buffer
+	a.NoError(game.Save(&buf))
+	loadedGame, err := Load(&buf)
+	a.NoError(err)
+
+	// The game should still be a hex game
+	a.IsType(&HexGame{}, loadedGame)
+	a.Equal(game.Appearance(0, 0, 16, 16), loadedGame.Appearance(0, 0, 16, 16))
+
+	// And so should its replay and clones
+	replayGame, err := loadedGame.Replay().Game()
+	a.NoError(err)
+	a.IsType(&HexGame{}, replayGame)
+	a.IsType(&HexGame{}, loadedGame.Clone())
+
+	// Both games should play the same
+	a.Equal(game.Uncover(0, 15), loadedGame.Uncover(0, 15))
+	a.Equal(game.Appearance(0, 0, 16, 16), loadedGame.Appearance(0, 0, 16, 16))
+}
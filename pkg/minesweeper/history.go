@@ -0,0 +1,108 @@
+package minesweeper
+
+// UndoPolicy controls which moves can be undone
+type UndoPolicy uint8
+
+const (
+	// UndoPolicyAlways allows any move to be undone, including the move that
+	// lost the game
+	UndoPolicyAlways = UndoPolicy(iota)
+
+	// UndoPolicyNotAfterLoss allows moves to be undone until the game is lost
+	UndoPolicyNotAfterLoss
+
+	// UndoPolicyNever doesn't allow moves to be undone
+	UndoPolicyNever
+)
+
+func (p UndoPolicy) String() string {
+	switch p {
+	case UndoPolicyAlways:
+		return "always"
+	case UndoPolicyNotAfterLoss:
+		return "not after loss"
+	case UndoPolicyNever:
+		return "never"
+	default:
+		return "unknown"
+	}
+}
+
+// WithUndoPolicy sets which moves can be undone, the default is
+// UndoPolicyAlways
+func WithUndoPolicy(policy UndoPolicy) Option {
+	return func(o *options) {
+		o.undoPolicy = policy
+	}
+}
+
+// move is a move in a game's history, each game type has its own type of
+// move which it knows how to undo and redo
+type move interface{}
+
+// history stores the moves made in a game
+type history struct {
+	policy UndoPolicy
+
+	// The moves that have been made, and the moves that have been undone
+	done, undone []move
+
+	// How many moves are being made. Moves can be made during other moves
+	// (such as the uncovers in a chord), but are recorded as one move
+	depth int
+}
+
+// begin starts recording a move, returns true if this is the outermost move
+func (h *history) begin() bool {
+	h.depth++
+	return h.depth == 1
+}
+
+// end stops recording a move, returns true if this was the outermost move
+func (h *history) end() bool {
+	h.depth--
+	return h.depth == 0
+}
+
+// push adds a move to the history, clearing any undone moves
+func (h *history) push(m move) {
+	h.done = append(h.done, m)
+	h.undone = nil
+}
+
+// clear removes every move from the history
+func (h *history) clear() {
+	h.done, h.undone = nil, nil
+}
+
+// clone returns a copy of the history. The moves themselves are never
+// modified, so they can be shared
+func (h *history) clone() history {
+	c := *h
+	c.done = append([]move(nil), h.done...)
+	c.undone = append([]move(nil), h.undone...)
+	return c
+}
+
+// undo returns the last move to be undone, if the policy allows it
+func (h *history) undo(state GameState) (move, bool) {
+	if len(h.done) == 0 || h.policy == UndoPolicyNever ||
+		(h.policy == UndoPolicyNotAfterLoss && state == GameStateLoss) {
+		return nil, false
+	}
+	m := h.done[len(h.done)-1]
+	h.done = h.done[:len(h.done)-1]
+	h.undone = append(h.undone, m)
+	return m, true
+}
+
+// redo returns the last undone move to be redone
+func (h *history) redo() (move, bool) {
+	if len(h.undone) == 0 || h.policy == UndoPolicyNever {
+		return nil, false
+	}
+	m := h.undone[len(h.undone)-1]
+	h.undone = h.undone[:len(h.undone)-1]
+	h.done = append(h.done, m)
+	return m, true
+}
@@ -0,0 +1,175 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// findMine returns the position of a mine in a populated finite game
+func findMine(g *FiniteGame) Pos {
+	for y, row := range g.field {
+		for x, tile := range row {
+			if tile.Type == TileTypeMine {
+				return Pos{x, y}
+			}
+		}
+	}
+	panic("no mines")
+}
+
+func TestFiniteUndoRedo(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame(16, 16, 40, WithSeed(1))
+	a.NoError(err)
+	a.False(game.Undo())
+
+	game.Uncover(8, 8)
+	afterUncover := game.Appearance(0, 0, 16, 16)
+	mine := findMine(game.(*FiniteGame))
+	game.Flag(mine.X, mine.Y)
+	a.Equal(float64(39), game.RemainingMines())
+
+	// Undo the flag
+	a.True(game.Undo())
+	a.Equal(float64(40), game.RemainingMines())
+	a.Equal(afterUncover, game.Appearance(0, 0, 16, 16))
+
+	// Undo the first move, which keeps the mines where they are
+	a.True(game.Undo())
+	a.Equal(GameStatePlaying, game.State())
+	for _, tileType := range game.Appearance(0, 0, 16, 16) {
+		a.Equal(TileTypeHidden, tileType)
+	}
+	a.False(game.Undo())
+	a.Equal(GameStateLoss, game.Clone().Uncover(mine.X, mine.Y))
+
+	// Redo both moves
+	a.True(game.Redo())
+	a.Equal(GameStatePlaying, game.State())
+	a.Equal(afterUncover, game.Appearance(0, 0, 16, 16))
+	a.True(game.Redo())
+	a.Equal(float64(39), game.RemainingMines())
+	a.False(game.Redo())
+
+	// Making a move forgets the undone moves
+	a.True(game.Undo())
+	game.Flag(0, 0)
+	a.False(game.Redo())
+}
+
+func TestFiniteUndoLoss(t *testing.T) {
+	a := assert.New(t)
+
+	for _, policy := range []UndoPolicy{
+		UndoPolicyAlways, UndoPolicyNotAfterLoss, UndoPolicyNever} {
+		game, err := NewGame(16, 16, 40, WithSeed(1), WithUndoPolicy(policy))
+		a.NoError(err)
+		game.Uncover(8, 8)
+		afterUncover := game.Appearance(0, 0, 16, 16)
+
+		mine := findMine(game.(*FiniteGame))
+		a.Equal(GameStateLoss, game.Uncover(mine.X, mine.Y))
+
+		if policy == UndoPolicyAlways {
+			a.True(game.Undo(), policy.String())
+			a.Equal(GameStatePlaying, game.State())
+			a.Equal(afterUncover, game.Appearance(0, 0, 16, 16))
+		} else {
+			a.False(game.Undo(), policy.String())
+			a.Equal(GameStateLoss, game.State())
+		}
+	}
+}
+
+func TestFiniteUndoChord(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame(16, 16, 40, WithSeed(0))
+	a.NoError(err)
+	g := game.(*FiniteGame)
+	g.Uncover(8, 8)
+
+	pos, mines, _, found := findChordable(g)
+	a.True(found)
+	for _, mine := range mines {
+		g.Flag(mine.X, mine.Y)
+	}
+	beforeChord := g.Appearance(0, 0, 16, 16)
+	g.Chord(pos.X, pos.Y)
+	a.NotEqual(beforeChord, g.Appearance(0, 0, 16, 16))
+
+	// The chord should be undone in one go
+	a.True(g.Undo())
+	a.Equal(beforeChord, g.Appearance(0, 0, 16, 16))
+}
+
+func TestInfiniteUndoRedo(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewInfiniteGame(40, WithSeed(1))
+	a.NoError(err)
+	game.Uncover(8, 8)
+	afterFirst := game.Appearance(-32, -32, 64, 64)
+	game.Uncover(-8, -8)
+	game.Flag(-20, -20)
+	afterFlag := game.Appearance(-32, -32, 64, 64)
+
+	a.True(game.Undo())
+	a.True(game.Undo())
+	a.Equal(afterFirst, game.Appearance(-32, -32, 64, 64))
+
+	a.True(game.Redo())
+	a.True(game.Redo())
+	a.Equal(afterFlag, game.Appearance(-32, -32, 64, 64))
+}
+
+func TestInfiniteUndoFirstMove(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 20; seed++ {
+		game, err := NewInfiniteGame(40, WithSeed(seed), WithLives(1))
+		a.NoError(err)
+		game.Uncover(8, 8)
+		afterFirst := game.Appearance(-16, -16, 48, 48)
+		startTime := game.StartTime()
+
+		// Undoing the first move hides the tiles, but the game carries on
+		// with the same clock
+		a.True(game.Undo())
+		a.Equal(GameStatePlaying, game.State())
+		a.Equal(startTime, game.StartTime())
+		for _, tileType := range game.Appearance(-16, -16, 48, 48) {
+			a.Equal(TileTypeHidden, tileType)
+		}
+		a.False(game.Undo())
+
+		// The first move is still kept clear of mines
+		a.Equal(GameStatePlaying, game.Uncover(8, 8))
+		a.Equal(afterFirst, game.Appearance(-16, -16, 48, 48))
+	}
+}
+
+func TestUndoPolicySerialiseRoundtrip(t *testing.T) {
+	a := assert.New(t)
+
+	finite, err := NewGame(16, 16, 40, WithUndoPolicy(UndoPolicyNotAfterLoss))
+	a.NoError(err)
+	infinite, err := NewInfiniteGame(40, WithUndoPolicy(UndoPolicyNever))
+	a.NoError(err)
+
+	for _, game := range []Game{finite, infinite} {
+		var buf bytes.Buffer
+		a.NoError(game.Save(&buf))
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+
+		switch g := game.(type) {
+		case *FiniteGame:
+			a.Equal(g.history.policy, loadedGame.(*FiniteGame).history.policy)
+		case *InfiniteGame:
+			a.Equal(g.history.policy, loadedGame.(*InfiniteGame).history.policy)
+		}
+	}
+}
@@ -1,129 +1,143 @@
 package minesweeper
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
+	"sort"
 	"time"
 )
 
 const ChunkSize = 16
 
+// chunkTile is a tile in an infinite game, see chunk.tile
 type chunkTile struct {
 	discovered bool
 	flagged    bool
 	mine       bool
+
+	// The number of neighbouring mines
+	number uint8
 }
 
-func (t chunkTile) toByte() (b byte) {
-	if t.discovered {
-		b |= 1 << 0
-	}
+// flags returns the number of flags on the tile, which is at most 1 as
+// infinite games don't have multimines
+func (t chunkTile) flags() int {
 	if t.flagged {
-		b |= 1 << 1
-	}
-	if t.mine {
-		b |= 1 << 2
+		return 1
 	}
-	return
+	return 0
 }
 
-func chunkTileFromByte(b byte) chunkTile {
+// chunk stores the tiles in a square of an infinite game, with a bitboard for
+// each of the tiles' states
+type chunk struct {
+	mines, discovered, flagged bitboard
+
+	// The number of mines next to each tile, including the mines in the
+	// neighbouring chunks, see InfiniteGame.countNeighbours
+	numbers [chunkTiles]uint8
+}
+
+// tile returns the tile at the given position in the chunk
+func (c *chunk) tile(p Pos) chunkTile {
+	i := tileIndex(p)
 	return chunkTile{
-		discovered: b&1 != 0,
-		flagged:    b&2 != 0,
-		mine:       b&4 != 0,
-	}
-}
-
-type chunk [ChunkSize][ChunkSize]chunkTile
-
-// randomChunk just creates a chunk with the given number of mines
-func randomChunk(numMines int) *chunk {
-	c := new(chunk)
-
-	// Place the mines
-	for i := 0; i < numMines; i++ {
-		// Loop until the mine is placed
-		for true {
-			// Find a random spot to place the mine
-			y := rand.Intn(ChunkSize)
-			x := rand.Intn(ChunkSize)
-			// If the spot doesn't already have a mine
-			if !c[y][x].mine {
-				// Set the tile as a mine
-				c[y][x].mine = true
-				// We placed a mine, break out of this loop
-				break
-			}
-		}
+		discovered: c.discovered.get(i),
+		flagged:    c.flagged.get(i),
+		mine:       c.mines.get(i),
+		number:     c.numbers[i],
 	}
-	return c
 }
 
-// randomChunkFromFirstMove creates a chunk with the given number of mines,
-// but doesn't put a mine at the given position
-func randomChunkFromFirstMove(numMines int, startPos Pos) *chunk {
-	c := new(chunk)
-
-	// Place the mines
-	for i := 0; i < numMines; i++ {
-		// Loop until the mine is placed
-		for true {
-			// Find a random spot to place the mine
-			y := rand.Intn(ChunkSize)
-			x := rand.Intn(ChunkSize)
-			// If the spot doesn't already have a mine and isn't near the
-			// start point
-			if !c[y][x].mine &&
-				!(x >= startPos.X-1 && x <= startPos.X+1 &&
-					y >= startPos.Y-1 && y <= startPos.Y+1) {
-				// Set the tile as a mine
-				c[y][x].mine = true
-				// We placed a mine, break out of this loop
-				break
-			}
-		}
+// setState sets whether the tile at the given position in the chunk is
+// discovered and flagged to match the given tile. The mine and number are
+// left alone, as they're generated
+func (c *chunk) setState(p Pos, t chunkTile) {
+	i := tileIndex(p)
+	c.discovered.set(i, t.discovered)
+	c.flagged.set(i, t.flagged)
+}
+
+// The points that make up an infinite game's score, see InfiniteGame.Score
+const (
+	// ScoreTileRevealed is given for every safe tile that's uncovered
+	ScoreTileRevealed = 1
+
+	// ScoreMineFlagged is given for every flagged mine in a chunk where all
+	// the safe tiles have been uncovered, as the flags are known to be right
+	ScoreMineFlagged = 5
+
+	// ScoreMineHit is given for every mine that's uncovered
+	ScoreMineHit = -50
+)
+
+// score returns the points for the tiles in the chunk
+func (c *chunk) score() int {
+	safe := c.mines.not()
+	score := c.discovered.and(safe).count()*ScoreTileRevealed +
+		c.discovered.and(c.mines).count()*ScoreMineHit
+	// The chunk is resolved if there's no safe tile left to uncover
+	if !safe.and(c.discovered.not()).any() {
+		score += c.flagged.and(c.mines).count() * ScoreMineFlagged
 	}
-	return c
+	return score
 }
 
-func (c *chunk) toBytes() (b []byte) {
-	b = make([]byte, ChunkSize*ChunkSize)
-	for y, row := range c {
-		for x, tile := range row {
-			b[(y*ChunkSize)+x] = tile.toByte()
-		}
+// randomMines places the given number of mines in a chunk, apart from the
+// tiles at the given (sorted) indexes
+func randomMines(rng *rand.Rand, numMines int, safe []int) (mines bitboard) {
+	for _, i := range sampleTiles(rng, chunkTiles, 1, numMines, safe) {
+		mines.set(i, true)
 	}
 	return
 }
 
-func chunkFromBytes(b []byte) (c *chunk) {
-	c = new(chunk)
-	for y := 0; y < ChunkSize; y++ {
-		for x := 0; x < ChunkSize; x++ {
-			c[y][x] = chunkTileFromByte(b[(y*ChunkSize)+x])
+// The number of bytes in the serialised player state of a chunk, where each
+// tile has 2 bits
+const chunkStateSize = chunkTiles / 4
+
+// touched returns whether any tile in the chunk has been discovered or flagged
+func (c *chunk) touched() bool {
+	return c.discovered.or(c.flagged).any()
+}
+
+// stateToBytes returns which tiles in the chunk have been discovered and
+// flagged. The mines aren't included, as they're generated from the seed
+func (c *chunk) stateToBytes() (b []byte) {
+	b = make([]byte, chunkStateSize)
+	for i := 0; i < chunkTiles; i++ {
+		if c.discovered.get(i) {
+			b[i/4] |= 1 << (i % 4 * 2)
+		}
+		if c.flagged.get(i) {
+			b[i/4] |= 2 << (i % 4 * 2)
 		}
 	}
 	return
 }
 
+// setStateFromBytes sets which tiles in the chunk have been discovered and
+// flagged, see stateToBytes
+func (c *chunk) setStateFromBytes(b []byte) {
+	for i := 0; i < chunkTiles; i++ {
+		bits := b[i/4] >> (i % 4 * 2)
+		c.discovered.set(i, bits&1 != 0)
+		c.flagged.set(i, bits&2 != 0)
+	}
+}
+
 func mod(a, b int) int {
 	return (a%b + b) % b
 }
 
 func fieldPos(p Pos) Pos {
-	// move negative positions over a chunk. This is because -0 == 0,
-	// so a pos of -1 would be in the same chunk as a pos of 1
-	if p.X < 0 {
-		p.X -= ChunkSize
-	}
-	if p.Y < 0 {
-		p.Y -= ChunkSize
-	}
-	return Pos{p.X / ChunkSize, p.Y / ChunkSize}
+	// Round negative positions down, instead of towards 0, as otherwise a pos
+	// of -1 would be in the same chunk as a pos of 1
+	return Pos{floorDiv(p.X, ChunkSize), floorDiv(p.Y, ChunkSize)}
 }
 
 // Converts the given x and y to the index of the chunk in the field,
@@ -136,17 +150,104 @@ func chunkPos(p Pos) (chunkIndex Pos, chunkPos Pos) {
 type InfiniteGame struct {
 	mineDensity int
 	field       map[Pos]*chunk
+	cache       chunkCache
 	state       GameState
 	startTime   time.Time
+	seed        int64
+	history     history
+	replay      *Replay
+
+	// The game's neighbourhood, or nil for NeighbourhoodMoore
+	neighbourhood Neighbourhood
+
+	// The position of the first move, if it's been made, and the tiles in
+	// each chunk kept free of mines for it, see WithFirstMovePolicy
+	firstMove       Pos
+	hasFirstMove    bool
+	firstMovePolicy firstMoveOptions
+	safeTiles       map[Pos][]int
+
+	// The number of mines in each chunk, or nil for the mine density in
+	// every chunk
+	density Density
+
+	// The number of lives the game starts with and has left, see WithLives
+	startingLives, lives int
+
+	// The game's score, and the part of it from each chunk
+	score       int
+	chunkScores map[Pos]int
+
+	// When the game ends, see WithTimeLimit and WithTargetScore, and when it
+	// ended
+	timeLimit   time.Duration
+	targetScore int
+	endTime     time.Time
+
+	// The tiles changed by the move being made (before they were changed),
+	// and the game before the move
+	changed    map[Pos]chunkTile
+	moveBefore infiniteSnapshot
+
+	// The functions subscribed to the game's events, and the events caused by
+	// the move being made
+	observers observers
+	events    moveEvents
 }
 
-func NewInfiniteGame(mineDensity int) (Game, error) {
+func NewInfiniteGame(mineDensity int, opts ...Option) (Game, error) {
+	o := newOptions(opts)
+
+	if o.neighbourhood != nil {
+		err := o.neighbourhood.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if o.maxMines != 1 {
+		return nil, fmt.Errorf("multimines can only be used with finite games")
+	}
+
+	err := o.firstMove.validate()
+	if err != nil {
+		return nil, err
+	}
+	lives, err := o.startingLives(UnlimitedLives)
+	if err != nil {
+		return nil, err
+	}
+	err = o.validateLimits(true)
+	if err != nil {
+		return nil, err
+	}
+	err = validateDensity(o.density)
+	if err != nil {
+		return nil, err
+	}
+
 	g := &InfiniteGame{
-		field: make(map[Pos]*chunk),
+		neighbourhood:   o.neighbourhood,
+		firstMovePolicy: o.firstMove,
+		startingLives:   lives,
+		timeLimit:       o.timeLimit,
+		targetScore:     o.targetScore,
+		density:         o.density,
+		field:           make(map[Pos]*chunk),
+		chunkScores:     make(map[Pos]int),
+		history:         history{policy: o.undoPolicy},
+	}
+
+	// Every chunk is generated from the seed
+	g.seed = o.seed
+
+	err = g.SetChunkCache(o.maxChunks, o.chunkStore)
+	if err != nil {
+		return nil, err
 	}
 
 	// Reset the game
-	err := g.Reset(mineDensity)
+	err = g.Reset(mineDensity)
 	if err != nil {
 		return nil, err
 	}
@@ -155,21 +256,64 @@ func NewInfiniteGame(mineDensity int) (Game, error) {
 }
 
 func (g *InfiniteGame) Reset(mineDensity int) error {
-	// Sanity check
-	if mineDensity > (ChunkSize*ChunkSize)-9 {
+	if mineDensity < 0 {
+		return fmt.Errorf("invalid mine density %d", mineDensity)
+	}
+	// Sanity check, there needs to be room for the tiles kept free of mines
+	// for the first move
+	safeTiles := min(g.firstMovePolicy.safeTiles(len(g.offsets())), chunkTiles)
+	if mineDensity > chunkTiles-safeTiles {
 		return fmt.Errorf(
-			"too many mines! mineDensity (%d) > %d * %d - 9",
-			mineDensity, ChunkSize, ChunkSize)
+			"too many mines! mineDensity (%d) > %d * %d - %d",
+			mineDensity, ChunkSize, ChunkSize, safeTiles)
+	}
+
+	// The discovered and flagged tiles are hidden, as the field starts again
+	if g.observers.active() {
+		err := g.rangeChunks(func(chunkIndex Pos, chunk *chunk) error {
+			for y := 0; y < ChunkSize; y++ {
+				for x := 0; x < ChunkSize; x++ {
+					tile := chunk.tile(Pos{x, y})
+					if tile.discovered || tile.flagged {
+						g.events.tile(Pos{chunkIndex.X*ChunkSize + x,
+							chunkIndex.Y*ChunkSize + y}, tile.discovered, false,
+							tile.flags(), 0, TileTypeHidden)
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			g.cache.fail(err)
+		}
 	}
 
+	// The chunks depend on the mine density and the first move, so they're
+	// generated again
+	g.clearChunks()
+	g.score, g.chunkScores = 0, make(map[Pos]int)
+	g.hasFirstMove, g.safeTiles = false, nil
+
 	g.mineDensity = mineDensity
+	g.lives = g.startingLives
+	g.endTime = time.Time{}
+	before := g.state
 	g.state = GameStateStart
+	g.history.clear()
 
+	err := g.startRecording()
+	if err != nil {
+		return err
+	}
+	g.events.emit(&g.observers, before, g.state)
 	return nil
 }
 
 func (g *InfiniteGame) Uncover(x, y int) (s GameState) {
+	g.record(ActionUncover, x, y)
+	g.beginMove()
 	defer func() {
+		g.endMove()
 		// The return value is the game's state
 		s = g.state
 	}()
@@ -183,29 +327,34 @@ func (g *InfiniteGame) Uncover(x, y int) (s GameState) {
 	}
 
 	{
-		chunkIndex, chunkPos := chunkPos(Pos{x, y})
-
-		// Get the chunk
-		chunk, ok := g.field[chunkIndex]
-		// Or create one if it doesn't exist
-		if !ok {
-			// Use a random chunk for the first move,
-			// so the user doesn't click a mine accidentally
-			chunk = randomChunkFromFirstMove(g.mineDensity, chunkPos)
-			g.field[chunkIndex] = chunk
+		// The mines are kept away from the first move, so the user doesn't
+		// click a mine accidentally
+		if !g.hasFirstMove {
+			g.firstMove, g.hasFirstMove = Pos{x, y}, true
+			g.protectFirstMove()
 		}
+		tile := g.get(Pos{x, y})
 
 		// If the cell is flagged, the cell is already discovered, or the game has ended
-		if chunk[chunkPos.Y][chunkPos.X].discovered || chunk[chunkPos.Y][chunkPos.X].flagged ||
-			g.state != GameStatePlaying {
+		if tile.discovered || tile.flagged || g.state != GameStatePlaying {
 			// Nothing needs to be done, so just return the game's state
 			return g.state
 		}
 
 		// If the tile is a mine
-		if chunk[chunkPos.Y][chunkPos.X].mine {
-			// This is infinite mode, so just set the tile as discovered and return
-			chunk[chunkPos.Y][chunkPos.X].discovered = true
+		if tile.mine {
+			// The mine explodes and costs a life. There's no end to the field,
+			// so the mines aren't revealed when the game is lost
+			g.events.mineHit(Pos{x, y})
+			tile.discovered = true
+			g.modify(Pos{x, y}, tile)
+			if g.lives != UnlimitedLives {
+				g.lives--
+			}
+			if g.lives == 0 {
+				g.state = GameStateLoss
+				g.endTime = time.Now()
+			}
 			return
 		}
 	}
@@ -219,23 +368,18 @@ func (g *InfiniteGame) Uncover(x, y int) (s GameState) {
 		pos := queue[len(queue)-1]
 		queue = queue[:len(queue)-1]
 
-		// Get the tile
-		tile := g.get(pos)
-
 		// Set the tile as discovered
+		tile := g.get(pos)
 		tile.discovered = true
-
-		// Get the neighbouring tiles
-		neighbouringTiles := g.neighbouringTiles(pos.X, pos.Y)
-		numNeighbouringMines := mineCount(neighbouringTiles)
+		g.modify(pos, tile)
 
 		// If the tile is empty
-		if numNeighbouringMines == 0 {
+		if tile.number == 0 {
 			// Uncover the neighbouring tiles
-			for _, neighbouringTile := range neighbouringTiles {
+			for _, neighbouringTile := range g.neighbouringTiles(pos.X, pos.Y) {
 				// Only uncover undiscovered tiles
 				if !neighbouringTile.discovered {
-					queue = append(queue, Pos{neighbouringTile.X, neighbouringTile.Y})
+					queue = append(queue, neighbouringTile.Pos)
 				}
 			}
 		}
@@ -244,8 +388,53 @@ func (g *InfiniteGame) Uncover(x, y int) (s GameState) {
 	return
 }
 
+func (g *InfiniteGame) Chord(x, y int) GameState {
+	g.record(ActionChord, x, y)
+	// The move is started first, as the time limit might have passed
+	g.beginMove()
+	defer g.endMove()
+
+	// If the game isn't being played
+	if g.state != GameStatePlaying {
+		// Nothing needs to be done, so just return the game's state
+		return g.state
+	}
+
+	// If the tile isn't a discovered number
+	tile := g.get(Pos{x, y})
+	if !tile.discovered || tile.mine {
+		return g.state
+	}
+
+	// Count the neighbouring flags, and the mines that have exploded
+	neighbouringTiles := g.neighbouringTiles(x, y)
+	flags := 0
+	for _, tile := range neighbouringTiles {
+		if tile.flagged || (tile.discovered && tile.mine) {
+			flags++
+		}
+	}
+
+	// If the flags don't match the number, nothing happens
+	if flags != mineCount(neighbouringTiles) {
+		return g.state
+	}
+
+	// Uncover the unflagged neighbours (Uncover skips the flagged ones)
+	for _, tile := range neighbouringTiles {
+		g.Uncover(tile.X, tile.Y)
+	}
+
+	return g.state
+}
+
 // Flag the tile at the given coordinate, always returns Int.MAX_VALUE
 func (g *InfiniteGame) Flag(x, y int) float64 {
+	g.record(ActionFlag, x, y)
+	// The move is started first, as the time limit might have passed
+	g.beginMove()
+	defer g.endMove()
+
 	// If the game has ended
 	if g.state > GameStatePlaying {
 		// Nothing needs to be done, so return
@@ -262,73 +451,285 @@ func (g *InfiniteGame) Flag(x, y int) float64 {
 
 	// Invert the flag field
 	tile.flagged = !tile.flagged
+	g.modify(Pos{x, y}, tile)
 
 	return g.RemainingMines()
 }
 
+func (g *InfiniteGame) Undo() bool {
+	g.record(ActionUndo, 0, 0)
+	m, ok := g.history.undo(g.state)
+	if ok {
+		m.(*infiniteMove).undo(g)
+	}
+	return ok
+}
+
+func (g *InfiniteGame) Redo() bool {
+	g.record(ActionRedo, 0, 0)
+	m, ok := g.history.redo()
+	if ok {
+		m.(*infiniteMove).redo(g)
+	}
+	return ok
+}
+
+func (g *InfiniteGame) Subscribe(f func(Event)) (unsubscribe func()) {
+	return g.observers.subscribe(f)
+}
+
+// Replay returns a copy of the recording of the moves made in the game
+func (g *InfiniteGame) Replay() *Replay {
+	return g.replay.copy()
+}
+
+// record adds an action to the game's replay, unless the action is part of
+// another move
+func (g *InfiniteGame) record(t ActionType, x, y int) {
+	if g.history.depth == 0 {
+		g.replay.record(t, x, y)
+	}
+}
+
+// startRecording starts a new replay of the game from its current state
+func (g *InfiniteGame) startRecording() error {
+	// Save the game without a replay
+	g.replay = nil
+	if !canSaveDensity(g.density) {
+		// The game can't be saved, so it can't be replayed either
+		return nil
+	}
+	var buf bytes.Buffer
+	err := g.Save(&buf)
+	if err != nil {
+		return err
+	}
+	g.replay = newReplay(buf.Bytes())
+	return nil
+}
+
+func (g *InfiniteGame) Clone() Game {
+	c := *g
+	c.field = make(map[Pos]*chunk, len(g.field))
+	for pos, chunk := range g.field {
+		chunkCopy := *chunk
+		c.field[pos] = &chunkCopy
+	}
+	c.cache = g.cloneCache()
+	c.chunkScores = make(map[Pos]int, len(g.chunkScores))
+	for pos, score := range g.chunkScores {
+		c.chunkScores[pos] = score
+	}
+	c.history = g.history.clone()
+	c.replay = g.replay.copy()
+	// The subscribers are for the original game
+	c.observers = observers{}
+	return &c
+}
+
 func (g *InfiniteGame) State() GameState {
+	// The time limit can pass between moves
+	if g.timeUp(time.Now()) {
+		return g.timeUpState()
+	}
 	return g.state
 }
 
+func (g *InfiniteGame) FirstMovePolicy() (FirstMovePolicy, int) {
+	return g.firstMovePolicy.policy, g.firstMovePolicy.minOpening
+}
+
+func (g *InfiniteGame) Lives() int {
+	return g.lives
+}
+
 func (g *InfiniteGame) StartTime() time.Time {
 	return g.startTime
 }
 
 func (g *InfiniteGame) SinceStart() time.Duration {
-	if g.state == GameStateStart {
+	switch g.state {
+	case GameStateStart:
 		return 0
+	case GameStatePlaying:
+		since := time.Now().Sub(g.startTime)
+		// The time stops when the time limit has passed
+		if g.timeLimit > 0 && since > g.timeLimit {
+			return g.timeLimit
+		}
+		return since
+	default:
+		return g.endTime.Sub(g.startTime)
 	}
-	return time.Now().Sub(g.startTime)
+}
+
+// TimeLimit returns the amount of time the game can be played for, or 0 if
+// there's no limit, see WithTimeLimit
+func (g *InfiniteGame) TimeLimit() time.Duration {
+	return g.timeLimit
+}
+
+// TargetScore returns the score that wins the game, or 0 if there's no
+// target, see WithTargetScore
+func (g *InfiniteGame) TargetScore() int {
+	return g.targetScore
+}
+
+// timeUp returns whether the game is being played, but its time limit has
+// passed by the given time
+func (g *InfiniteGame) timeUp(now time.Time) bool {
+	return g.state == GameStatePlaying && g.timeLimit > 0 &&
+		now.Sub(g.startTime) >= g.timeLimit
+}
+
+// timeUpState returns the state the game ends in when the time runs out. If
+// the game has a target score, it would have been won when the score was
+// reached, so the game is lost
+func (g *InfiniteGame) timeUpState() GameState {
+	if g.targetScore > 0 {
+		return GameStateLoss
+	}
+	return GameStateWin
+}
+
+// checkEnd ends the game if its time limit has passed by the given time, or
+// its target score has been reached
+func (g *InfiniteGame) checkEnd(now time.Time) {
+	switch {
+	case g.timeUp(now):
+		g.state = g.timeUpState()
+		g.endTime = g.startTime.Add(g.timeLimit)
+	case g.state == GameStatePlaying && g.targetScore > 0 &&
+		g.score >= g.targetScore:
+		g.state = GameStateWin
+		g.endTime = now
+	}
+}
+
+// timeLeft returns how long there is until the game's time limit runs out
+// after the given time, or false if it's not being played with a time limit
+func (g *InfiniteGame) timeLeft(now time.Time) (time.Duration, bool) {
+	if g.state != GameStatePlaying || g.timeLimit == 0 {
+		return 0, false
+	}
+	return g.startTime.Add(g.timeLimit).Sub(now), true
+}
+
+// endIfTimeUp ends the game if its time limit has passed by the given time,
+// and emits the change in state. It's for ending the game between moves
+func (g *InfiniteGame) endIfTimeUp(now time.Time) {
+	if !g.timeUp(now) {
+		return
+	}
+	before := g.state
+	g.checkEnd(now)
+	g.observers.emit(StateChangedEvent{before, g.state})
 }
 
 func (g *InfiniteGame) MineDensity() int {
 	return g.mineDensity
 }
 
+// ChunkMines returns the number of mines in the chunk at the given index (the
+// chunk's position divided by ChunkSize), see WithDensity
+func (g *InfiniteGame) ChunkMines(chunkIndex Pos) int {
+	if g.density == nil {
+		return max(g.mineDensity, 0)
+	}
+	// There needs to be room for the tiles kept free of mines for the first
+	// move, in case they're in the chunk
+	maxMines := chunkTiles - g.firstMovePolicy.safeTiles(len(g.offsets()))
+	return max(min(g.density.Mines(chunkIndex, g.mineDensity), maxMines), 0)
+}
+
+// offsets returns the offsets of the tiles next to a tile
+func (g *InfiniteGame) offsets() Neighbourhood {
+	if g.neighbourhood != nil {
+		return g.neighbourhood
+	}
+	return NeighbourhoodMoore
+}
+
+// Neighbours returns the positions of the tiles next to the given coordinate
+func (g *InfiniteGame) Neighbours(x, y int) []Pos {
+	offsets := g.offsets()
+	neighbours := make([]Pos, len(offsets))
+	for i, offset := range offsets {
+		neighbours[i] = Pos{x + offset.X, y + offset.Y}
+	}
+	return neighbours
+}
+
+// Seed returns the seed the game's chunks are generated from
+func (g *InfiniteGame) Seed() int64 {
+	return g.seed
+}
+
 func (g *InfiniteGame) RemainingMines() float64 {
 	return math.Inf(1)
 }
 
+// Score returns the game's score. Every safe tile uncovered is worth
+// ScoreTileRevealed, every mine uncovered is worth ScoreMineHit, and once all
+// the safe tiles in a chunk are uncovered, every flagged mine in the chunk is
+// worth ScoreMineFlagged
+func (g *InfiniteGame) Score() int {
+	return g.score
+}
+
+// updateScore works out the score again for the chunks with the given tiles
+// in
+func (g *InfiniteGame) updateScore(changes []infiniteTileChange) {
+	updated := make(map[Pos]bool)
+	for _, c := range changes {
+		chunkIndex := fieldPos(c.Pos)
+		if updated[chunkIndex] {
+			continue
+		}
+		updated[chunkIndex] = true
+		chunkScore := g.field[chunkIndex].score()
+		g.score += chunkScore - g.chunkScores[chunkIndex]
+		// Only the chunks that score are kept, so the map doesn't grow with
+		// every chunk
+		if chunkScore != 0 {
+			g.chunkScores[chunkIndex] = chunkScore
+		} else {
+			delete(g.chunkScores, chunkIndex)
+		}
+	}
+}
+
 func (g *InfiniteGame) Appearance(x, y, w, h int) (appearance map[Pos]TileType) {
 	maxX, maxY := x+w, y+h
 
 	appearance = make(map[Pos]TileType, w*h)
-	for y := y; y < maxY; y++ {
-		for x := x; x < maxX; x++ {
-			// Get the chunk
-			pos := Pos{x, y}
-			chunkIndex, chunkPos := chunkPos(pos)
-			chunk, ok := g.field[chunkIndex]
-
-			// If the chunk doesn't exist, then the tile is hidden
-			if !ok {
-				appearance[pos] = TileTypeHidden
-			} else {
-				// Get the tile
-				tile := chunk[chunkPos.Y][chunkPos.X]
-
-				// If the tile hasn't been discovered
-				if !tile.discovered {
-					// If the tile is flagged
-					if tile.flagged {
-						// Set the tile as flagged
-						appearance[pos] = TileTypeFlag
-					} else {
-						// Set the tile as hidden
+	if w <= 0 || h <= 0 {
+		return appearance
+	}
+	// The game isn't changed, so it can be read while it's being read
+	// elsewhere
+	v := g.view()
+
+	// Go through the chunks in the rect, so each chunk is only looked up once
+	minChunk, maxChunk := fieldPos(Pos{x, y}), fieldPos(Pos{maxX - 1, maxY - 1})
+	for chunkY := minChunk.Y; chunkY <= maxChunk.Y; chunkY++ {
+		for chunkX := minChunk.X; chunkX <= maxChunk.X; chunkX++ {
+			chunk := v.chunk(Pos{chunkX, chunkY})
+
+			// The part of the rect in the chunk
+			startX, startY := max(x, chunkX*ChunkSize), max(y, chunkY*ChunkSize)
+			endX := min(maxX, (chunkX+1)*ChunkSize)
+			endY := min(maxY, (chunkY+1)*ChunkSize)
+			for tileY := startY; tileY < endY; tileY++ {
+				for tileX := startX; tileX < endX; tileX++ {
+					pos := Pos{tileX, tileY}
+					// If the chunk doesn't exist, then the tile is hidden
+					if chunk == nil {
 						appearance[pos] = TileTypeHidden
+					} else {
+						appearance[pos] = chunk.tile(Pos{tileX - chunkX*ChunkSize,
+							tileY - chunkY*ChunkSize}).appearance()
 					}
-
-				} else if tile.mine {
-					appearance[pos] = TileTypeMine
-
-				} else {
-					// todo this is pretty slow, could be cached
-					neighbouringTiles := g.neighbouringTiles(x, y)
-					numNeighbouringMines := mineCount(neighbouringTiles)
-
-					// Set the appearance from the neighbouring mines
-					appearance[pos] = TileType(int(TileTypeEmpty) +
-						numNeighbouringMines)
 				}
 			}
 		}
@@ -336,14 +737,46 @@ func (g *InfiniteGame) Appearance(x, y, w, h int) (appearance map[Pos]TileType)
 	return appearance
 }
 
+// appearance returns the appearance of the tile, see InfiniteGame.Appearance
+func (tile chunkTile) appearance() TileType {
+	// If the tile hasn't been discovered
+	if !tile.discovered {
+		// If the tile is flagged
+		if tile.flagged {
+			return TileTypeFlag
+		}
+		return TileTypeHidden
+	}
+
+	// The only discovered mines are the ones that exploded
+	if tile.mine {
+		return TileTypeExploded
+	}
+
+	// The appearance is from the neighbouring mines
+	return TileTypeNumber(int(tile.number))
+}
+
 func (g *InfiniteGame) Save(w io.Writer) error {
-	err := newHeader(saveHeaderInfiniteGameType).save(w)
+	// The player's state in a chunk might have been lost
+	if g.cache.err != nil {
+		return g.cache.err
+	}
+	// Check the density can be saved before writing anything
+	if !canSaveDensity(g.density) {
+		return ErrCustomDensity
+	}
+
+	h := newHeader(saveHeaderInfiniteGameType)
+	g.firstMovePolicy.setHeader(&h)
+	err := h.save(w)
 	if err != nil {
 		return err
 	}
 
-	// Write the mine density and start time as 64 bit ints
-	for _, data := range []int64{int64(g.mineDensity), g.startTime.UnixNano()} {
+	// Write the mine density, start time and seed as 64 bit ints
+	for _, data := range []int64{int64(g.mineDensity), g.startTime.UnixNano(),
+		g.seed} {
 		err = binary.Write(w, serialiseByteOrder, data)
 		if err != nil {
 			return err
@@ -356,14 +789,77 @@ func (g *InfiniteGame) Save(w io.Writer) error {
 		return err
 	}
 
+	// Write the undo policy
+	err = binary.Write(w, serialiseByteOrder, g.history.policy)
+	if err != nil {
+		return err
+	}
+
+	// Write the neighbourhood and the lives
+	err = saveNeighbourhood(w, g.neighbourhood)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(w, serialiseByteOrder,
+		[]int64{int64(g.startingLives), int64(g.lives)})
+	if err != nil {
+		return err
+	}
+
+	// Write the time limit, target score and end time
+	err = binary.Write(w, serialiseByteOrder,
+		[]int64{int64(g.timeLimit), int64(g.targetScore), g.endTime.UnixNano()})
+	if err != nil {
+		return err
+	}
+
+	// Write the density
+	err = saveDensity(w, g.density)
+	if err != nil {
+		return err
+	}
+
+	// Write the first move
+	err = binary.Write(w, serialiseByteOrder, g.hasFirstMove)
+	if err != nil {
+		return err
+	}
+	err = binary.Write(w, serialiseByteOrder,
+		[]int64{int64(g.firstMove.X), int64(g.firstMove.Y)})
+	if err != nil {
+		return err
+	}
+
+	// The mines are generated from the seed, so only the chunks the player
+	// has touched need saving
+	var touched []Pos
+	states := make(map[Pos][]byte)
+	err = g.rangeChunks(func(chunkIndex Pos, chunk *chunk) error {
+		if chunk.touched() {
+			touched = append(touched, chunkIndex)
+			states[chunkIndex] = chunk.stateToBytes()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// The chunks are sorted, so the same game is always saved the same way
+	sort.Slice(touched, func(i, j int) bool {
+		if touched[i].Y != touched[j].Y {
+			return touched[i].Y < touched[j].Y
+		}
+		return touched[i].X < touched[j].X
+	})
+
 	// Write the number of chunks
-	err = binary.Write(w, serialiseByteOrder, int64(len(g.field)))
+	err = binary.Write(w, serialiseByteOrder, int64(len(touched)))
 	if err != nil {
 		return err
 	}
 
 	// Write the chunks
-	for pos, chunk := range g.field {
+	for _, pos := range touched {
 		// Write the position
 		for _, data := range []int64{int64(pos.X), int64(pos.Y)} {
 			err = binary.Write(w, serialiseByteOrder, data)
@@ -372,22 +868,30 @@ func (g *InfiniteGame) Save(w io.Writer) error {
 			}
 		}
 
-		// Write the chunk data
-		_, err = w.Write(chunk.toBytes())
+		// Write the player's state in the chunk
+		_, err = w.Write(states[pos])
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	// no need to save g.score, it can be calculated from g.field
+
+	// Write the replay
+	return saveGameReplay(w, g.replay)
 }
 
-func loadInfinite(r io.Reader) (Game, error) {
+func loadInfinite(r io.Reader, h saveHeader) (Game, error) {
 	g := &InfiniteGame{}
+	var err error
+	g.firstMovePolicy, err = firstMoveFromHeader(h)
+	if err != nil {
+		return nil, err
+	}
 
-	// Read the first 2 fields as 64 bit ints
-	fields := make([]int64, 2)
-	err := binary.Read(r, serialiseByteOrder, fields)
+	// Read the first 3 fields as 64 bit ints
+	fields := make([]int64, 3)
+	err = binary.Read(r, serialiseByteOrder, fields)
 	if err != nil {
 		return nil, err
 	}
@@ -395,6 +899,7 @@ func loadInfinite(r io.Reader) (Game, error) {
 	// todo int overflow
 	g.mineDensity = int(fields[0])
 	g.startTime = time.Unix(0, fields[1])
+	g.seed = fields[2]
 
 	// Read the state byte
 	// todo handle invalid state
@@ -405,6 +910,58 @@ func loadInfinite(r io.Reader) (Game, error) {
 	}
 	g.state = GameState(state)
 
+	// Read the undo policy
+	err = binary.Read(r, serialiseByteOrder, &g.history.policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the neighbourhood and the lives
+	g.neighbourhood, err = loadNeighbourhood(r)
+	if err != nil {
+		return nil, err
+	}
+	lives := make([]int64, 2)
+	err = binary.Read(r, serialiseByteOrder, lives)
+	if err != nil {
+		return nil, err
+	}
+	// todo int overflow
+	g.startingLives, g.lives = int(lives[0]), int(lives[1])
+
+	// Read the time limit, target score and end time
+	limits := make([]int64, 3)
+	err = binary.Read(r, serialiseByteOrder, limits)
+	if err != nil {
+		return nil, err
+	}
+	g.timeLimit = time.Duration(limits[0])
+	// todo int overflow
+	g.targetScore = int(limits[1])
+	g.endTime = time.Unix(0, limits[2])
+
+	// Read the density
+	g.density, err = loadDensity(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the first move
+	err = binary.Read(r, serialiseByteOrder, &g.hasFirstMove)
+	if err != nil {
+		return nil, err
+	}
+	firstMove := make([]int64, 2)
+	err = binary.Read(r, serialiseByteOrder, firstMove)
+	if err != nil {
+		return nil, err
+	}
+	// todo int overflow
+	g.firstMove = Pos{int(firstMove[0]), int(firstMove[1])}
+	if g.hasFirstMove {
+		g.protectFirstMove()
+	}
+
 	// Read the number of chunks
 	var numChunks int64
 	err = binary.Read(r, serialiseByteOrder, &numChunks)
@@ -422,14 +979,30 @@ func loadInfinite(r io.Reader) (Game, error) {
 			return nil, err
 		}
 
-		// Read the chunk
-		chunkBytes := make([]byte, ChunkSize*ChunkSize)
-		_, err = r.Read(chunkBytes)
+		// Generate the chunk, and restore the player's state in it
+		stateBytes := make([]byte, chunkStateSize)
+		_, err = io.ReadFull(r, stateBytes)
 		if err != nil {
 			return nil, err
 		}
 		// todo int overflow
-		g.field[Pos{X: int(pos[0]), Y: int(pos[1])}] = chunkFromBytes(chunkBytes)
+		chunkIndex := Pos{X: int(pos[0]), Y: int(pos[1])}
+		chunk := g.newChunk(chunkIndex)
+		chunk.setStateFromBytes(stateBytes)
+		g.field[chunkIndex] = chunk
+	}
+
+	// Calculate the score
+	g.chunkScores = make(map[Pos]int, len(g.field))
+	for pos, chunk := range g.field {
+		g.chunkScores[pos] = chunk.score()
+		g.score += g.chunkScores[pos]
+	}
+
+	// Read the replay
+	g.replay, err = loadGameReplay(r)
+	if err != nil {
+		return nil, err
 	}
 
 	return g, nil
@@ -437,41 +1010,304 @@ func loadInfinite(r io.Reader) (Game, error) {
 
 // Retrieve the tile at the given position, and automatically populate the
 // chunk the tile is in if it doesn't exist yet
-func (g *InfiniteGame) get(p Pos) *chunkTile {
+func (g *InfiniteGame) get(p Pos) chunkTile {
 	chunkIndex, chunkPos := chunkPos(p)
+	return g.getChunk(chunkIndex).tile(chunkPos)
+}
 
-	// Get the chunk
-	chunk, ok := g.field[chunkIndex]
+// getChunk returns the chunk at the given index, creating it if it doesn't
+// exist yet
+func (g *InfiniteGame) getChunk(chunkIndex Pos) *chunk {
+	chunk := g.existingChunk(chunkIndex)
 	// Or create one if it doesn't exist
-	if !ok {
-		chunk = randomChunk(g.mineDensity)
+	if chunk == nil {
+		chunk = g.newChunk(chunkIndex)
 		g.field[chunkIndex] = chunk
 	}
+	g.usedChunk(chunkIndex)
+	return chunk
+}
+
+// chunkView looks up an infinite game's chunks without changing the game. The
+// chunks that aren't in memory are loaded from the store, but aren't added to
+// the field
+type chunkView struct {
+	g *InfiniteGame
+
+	// The chunks that aren't in memory, or nil if they don't exist
+	chunks map[Pos]*chunk
+}
+
+func (g *InfiniteGame) view() *chunkView {
+	return &chunkView{
+		g:      g,
+		chunks: make(map[Pos]*chunk),
+	}
+}
+
+// chunk returns the chunk at the given index, or nil if it doesn't exist in
+// the game (in memory or in the store)
+func (v *chunkView) chunk(chunkIndex Pos) *chunk {
+	if chunk, ok := v.g.field[chunkIndex]; ok {
+		return chunk
+	}
+	if chunk, ok := v.chunks[chunkIndex]; ok {
+		return chunk
+	}
 
-	return &chunk[chunkPos.Y][chunkPos.X]
+	var chunk *chunk
+	if v.g.cache.stored[chunkIndex] != nil {
+		// Errors can't be recorded without changing the game, but the chunk
+		// would fail to load for the next move too
+		state, err := v.g.cache.load(chunkIndex)
+		if err == nil && state != nil {
+			chunk = v.g.newChunk(chunkIndex)
+			chunk.setStateFromBytes(state)
+		}
+	}
+	v.chunks[chunkIndex] = chunk
+	return chunk
+}
+
+// newChunk generates the chunk at the given index, see generateMines
+func (g *InfiniteGame) newChunk(chunkIndex Pos) *chunk {
+	c := &chunk{mines: g.generateMines(chunkIndex)}
+	g.countNeighbours(chunkIndex, c)
+	return c
+}
+
+// generateMines generates the mines in the chunk at the given index. Each
+// chunk has its own random numbers, from the seed and the chunk's index, so
+// the chunks are the same whichever order they're generated in. The only
+// other thing a chunk depends on is the first move, which the tiles kept free
+// of mines depend on
+func (g *InfiniteGame) generateMines(chunkIndex Pos) bitboard {
+	rng := rand.New(&splitMixSource{hashPos(g.seed, chunkIndex)})
+	return randomMines(rng, g.ChunkMines(chunkIndex), g.safeTiles[chunkIndex])
+}
+
+// protectFirstMove works out which tiles are kept free of mines for the first
+// move. Nothing has been uncovered before the first move, so the chunks in
+// memory can be generated again without the player noticing
+func (g *InfiniteGame) protectFirstMove() {
+	// The area depends on the seed and the first move, so it's the same when
+	// the game is loaded
+	rng := rand.New(&splitMixSource{hashPos(^g.seed, g.firstMove)})
+	neighbours := func(p Pos) []Pos {
+		return g.Neighbours(p.X, p.Y)
+	}
+	g.safeTiles = make(map[Pos][]int)
+	for _, p := range g.firstMovePolicy.safeArea(g.firstMove, neighbours, rng) {
+		chunkIndex, chunkPos := chunkPos(p)
+		g.safeTiles[chunkIndex] = append(g.safeTiles[chunkIndex],
+			tileIndex(chunkPos))
+	}
+
+	recount := make(map[Pos]bool)
+	for chunkIndex, safe := range g.safeTiles {
+		sort.Ints(safe)
+		if chunk, ok := g.field[chunkIndex]; ok {
+			chunk.mines = g.generateMines(chunkIndex)
+		}
+		// The chunks around it counted its mines
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				recount[Pos{chunkIndex.X + dx, chunkIndex.Y + dy}] = true
+			}
+		}
+	}
+	for chunkIndex := range recount {
+		if chunk, ok := g.field[chunkIndex]; ok {
+			g.countNeighbours(chunkIndex, chunk)
+		}
+	}
+}
+
+// countNeighbours counts the mines next to each tile in the given chunk. The
+// mines in the chunks around it are taken from memory, or generated if the
+// chunks aren't in memory, so the numbers don't change when they're created
+func (g *InfiniteGame) countNeighbours(chunkIndex Pos, c *chunk) {
+	// The mines in the chunk and the chunks around it, as they're needed
+	var around [3][3]*bitboard
+	around[1][1] = &c.mines
+	mines := func(dx, dy int) *bitboard {
+		if around[dy+1][dx+1] == nil {
+			index := Pos{chunkIndex.X + dx, chunkIndex.Y + dy}
+			if neighbour, ok := g.field[index]; ok {
+				around[dy+1][dx+1] = &neighbour.mines
+			} else {
+				generated := g.generateMines(index)
+				around[dy+1][dx+1] = &generated
+			}
+		}
+		return around[dy+1][dx+1]
+	}
+
+	offsets := g.offsets()
+	for y := 0; y < ChunkSize; y++ {
+		for x := 0; x < ChunkSize; x++ {
+			number := 0
+			for _, offset := range offsets {
+				// The neighbourhood is at most a chunk away
+				nx, ny := x+offset.X, y+offset.Y
+				b := mines(floorDiv(nx, ChunkSize), floorDiv(ny, ChunkSize))
+				if b.get(tileIndex(Pos{mod(nx, ChunkSize), mod(ny, ChunkSize)})) {
+					number++
+				}
+			}
+			c.numbers[tileIndex(Pos{x, y})] = uint8(number)
+		}
+	}
+}
+
+// infiniteSnapshot is the state of an InfiniteGame that moves change, other
+// than the tiles
+type infiniteSnapshot struct {
+	state     GameState
+	startTime time.Time
+	lives     int
+	endTime   time.Time
+}
+
+func (g *InfiniteGame) snapshot() infiniteSnapshot {
+	return infiniteSnapshot{
+		state:     g.state,
+		startTime: g.startTime,
+		lives:     g.lives,
+		endTime:   g.endTime,
+	}
+}
+
+func (g *InfiniteGame) restore(s infiniteSnapshot) {
+	g.state = s.state
+	g.startTime = s.startTime
+	g.lives = s.lives
+	g.endTime = s.endTime
+}
+
+type infiniteTileChange struct {
+	Pos
+	before, after chunkTile
+}
+
+// infiniteMove is a move in an InfiniteGame's history. Chunks created by the
+// move aren't removed when it's undone, only the tiles are changed back
+type infiniteMove struct {
+	before, after infiniteSnapshot
+	changes       []infiniteTileChange
+}
+
+func (m *infiniteMove) undo(g *InfiniteGame) {
+	for _, c := range m.changes {
+		g.set(c.Pos, c.before)
+	}
+	g.updateScore(m.changes)
+	g.restore(m.before)
+	g.emitChanges(m.changes, true, m.after.state)
+	g.trimChunks()
+}
+
+func (m *infiniteMove) redo(g *InfiniteGame) {
+	for _, c := range m.changes {
+		g.set(c.Pos, c.after)
+	}
+	g.updateScore(m.changes)
+	g.restore(m.after)
+	g.emitChanges(m.changes, false, m.before.state)
+	g.trimChunks()
+}
+
+// emitChanges emits the events for the given tile changes (or for undoing
+// them), and the change from the given state
+func (g *InfiniteGame) emitChanges(changes []infiniteTileChange, undo bool,
+	before GameState) {
+	if g.observers.active() {
+		for _, c := range changes {
+			from, to := c.before, c.after
+			if undo {
+				from, to = to, from
+			}
+			g.events.tile(c.Pos, from.discovered, to.discovered,
+				from.flags(), to.flags(), to.appearance())
+		}
+	}
+	g.events.emit(&g.observers, before, g.state)
+}
+
+// beginMove starts recording a move for the game's history
+func (g *InfiniteGame) beginMove() {
+	if g.history.begin() {
+		g.changed = make(map[Pos]chunkTile)
+		g.moveBefore = g.snapshot()
+		// The time limit might have passed since the last move
+		g.checkEnd(time.Now())
+	}
+}
+
+// endMove stops recording a move, and adds it to the game's history if
+// anything changed
+func (g *InfiniteGame) endMove() {
+	if !g.history.end() {
+		return
+	}
+
+	m := &infiniteMove{before: g.moveBefore}
+	for pos, before := range g.changed {
+		after := g.get(pos)
+		if before != after {
+			m.changes = append(m.changes, infiniteTileChange{pos, before, after})
+		}
+	}
+	g.changed = nil
+	g.updateScore(m.changes)
+	// The move might have reached the target score
+	g.checkEnd(time.Now())
+	m.after = g.snapshot()
+
+	// The mines around the first move stay clear if it's undone, but the
+	// next move wouldn't be the first move again, so undoing it goes back to
+	// the game being played, not to the start (like a FiniteGame). The clock
+	// carries on too
+	before := m.before.state
+	if before == GameStateStart {
+		m.before.state = GameStatePlaying
+		m.before.startTime = m.after.startTime
+	}
+
+	if len(m.changes) > 0 || m.before != m.after {
+		g.history.push(m)
+	}
+	g.emitChanges(m.changes, false, before)
+	g.trimChunks()
+}
+
+// set sets whether the tile at the given position is discovered and flagged
+// to match the given tile
+func (g *InfiniteGame) set(p Pos, t chunkTile) {
+	chunkIndex, chunkPos := chunkPos(p)
+	g.getChunk(chunkIndex).setState(chunkPos, t)
+}
+
+// modify sets the tile at the given position like set, remembering what it
+// was before for the move's history
+func (g *InfiniteGame) modify(p Pos, t chunkTile) {
+	if _, ok := g.changed[p]; !ok && g.changed != nil {
+		g.changed[p] = g.get(p)
+	}
+	g.set(p, t)
 }
 
 type chunkTileAndPos struct {
 	Pos
-	*chunkTile
+	chunkTile
 }
 
 func (g *InfiniteGame) neighbouringTiles(x, y int) (tiles []chunkTileAndPos) {
+	neighbours := g.Neighbours(x, y)
 	// The array of neighbouring tiles
-	tiles = make([]chunkTileAndPos, 0, 8)
-	// Iterate over the 3x3 around the tile
-	for neighbourY := y - 1; neighbourY <= y+1; neighbourY++ {
-		for neighbourX := x - 1; neighbourX <= x+1; neighbourX++ {
-			// Skip if the tile is the center tile
-			if neighbourY == y && neighbourX == x {
-				continue
-			}
-			// Add the tile
-			tiles = append(tiles, chunkTileAndPos{
-				Pos:       Pos{X: neighbourX, Y: neighbourY},
-				chunkTile: g.get(Pos{neighbourX, neighbourY}),
-			})
-		}
+	tiles = make([]chunkTileAndPos, len(neighbours))
+	for i, pos := range neighbours {
+		tiles[i] = chunkTileAndPos{Pos: pos, chunkTile: g.get(pos)}
 	}
 	return
 }
@@ -31,7 +31,363 @@ func TestInfiniteSerialiseRoundtrip(t *testing.T) {
 		a.Equal(expected.mineDensity, actual.mineDensity)
 		a.Equal(expected.startTime.Unix(), actual.startTime.Unix())
 		a.Equal(expected.state, actual.state)
-		a.Equal(expected.field, actual.field)
+		a.Equal(expected.seed, actual.seed)
+		a.Equal(game.Appearance(-32, -32, 80, 64),
+			loadedGame.Appearance(-32, -32, 80, 64))
 	}
 
 }
+
+func TestInfiniteSeed(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewInfiniteGame(40, WithSeed(seed))
+		a.NoError(err)
+		a.Equal(seed, game.(*InfiniteGame).Seed())
+		game.Uncover(8, 8)
+
+		// Save the game part way through
+		var buf bytes.Buffer
+		a.NoError(game.Save(&buf))
+
+		sameSeedGame, err := NewInfiniteGame(40, WithSeed(seed))
+		a.NoError(err)
+		sameSeedGame.Uncover(8, 8)
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+
+		for _, g := range []Game{game, sameSeedGame, loadedGame} {
+			g.Uncover(-8, -8)
+			g.Uncover(24, 8)
+		}
+
+		expected := game.Appearance(-32, -32, 80, 64)
+		a.Equal(expected, sameSeedGame.Appearance(-32, -32, 80, 64))
+		a.Equal(expected, loadedGame.Appearance(-32, -32, 80, 64))
+	}
+}
+
+func TestInfiniteChunkOrder(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewInfiniteGame(40, WithSeed(seed))
+		a.NoError(err)
+		otherGame, err := NewInfiniteGame(40, WithSeed(seed))
+		a.NoError(err)
+		g, other := game.(*InfiniteGame), otherGame.(*InfiniteGame)
+
+		// The first move is the same, but the other chunks are generated
+		// in a different order
+		g.Uncover(8, 8)
+		other.Uncover(8, 8)
+		chunks := []Pos{{1, 0}, {-1, -1}, {3, 2}, {0, -4}}
+		for _, c := range chunks {
+			g.get(Pos{c.X * ChunkSize, c.Y * ChunkSize})
+		}
+		for i := len(chunks) - 1; i >= 0; i-- {
+			other.get(Pos{chunks[i].X * ChunkSize, chunks[i].Y * ChunkSize})
+		}
+		a.Equal(g.field, other.field)
+	}
+}
+
+func TestInfiniteReset(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewInfiniteGame(40, WithSeed(1))
+	a.NoError(err)
+	game.Uncover(8, 8)
+	game.Flag(-1, -1)
+
+	// Resetting hides everything, but keeps the seed
+	a.NoError(game.Reset(40))
+	a.Equal(GameStateStart, game.State())
+	a.Equal(int64(1), game.(*InfiniteGame).Seed())
+	a.Equal(0, game.(*InfiniteGame).Score())
+	for _, tile := range game.Appearance(-16, -16, 48, 48) {
+		a.Equal(TileTypeHidden, tile)
+	}
+
+	// The same first move makes the same field as a new game
+	newGame, err := NewInfiniteGame(40, WithSeed(1))
+	a.NoError(err)
+	game.Uncover(8, 8)
+	newGame.Uncover(8, 8)
+	a.Equal(newGame.Appearance(-16, -16, 48, 48), game.Appearance(-16, -16, 48, 48))
+
+	// A negative density isn't valid, and leaves the game as it was
+	a.Error(game.Reset(-1))
+	a.Equal(GameStatePlaying, game.State())
+	_, err = NewInfiniteGame(-1)
+	a.Error(err)
+}
+
+func TestInfiniteChord(t *testing.T) {
+	a := assert.New(t)
+
+	chorded := 0
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewInfiniteGame(40, WithSeed(seed))
+		a.NoError(err)
+		g := game.(*InfiniteGame)
+		g.Uncover(8, 8)
+
+		// Only chord once, so the flags don't interfere
+	search:
+		for y := 0; y < ChunkSize; y++ {
+			for x := 0; x < ChunkSize; x++ {
+				if !g.get(Pos{x, y}).discovered {
+					continue
+				}
+				neighbouringTiles := g.neighbouringTiles(x, y)
+				number := mineCount(neighbouringTiles)
+
+				// Flag safe tiles instead of the mines
+				var flagged []chunkTileAndPos
+				for _, neighbour := range neighbouringTiles {
+					if !neighbour.discovered && !neighbour.mine && len(flagged) < number {
+						flagged = append(flagged, neighbour)
+					}
+				}
+				if number == 0 || len(flagged) != number {
+					continue
+				}
+				for _, tile := range flagged {
+					g.Flag(tile.X, tile.Y)
+				}
+
+				// Chording should uncover the mines, but the game carries on
+				a.Equal(GameStatePlaying, g.Chord(x, y))
+				for _, neighbour := range neighbouringTiles {
+					if neighbour.mine {
+						a.True(g.get(neighbour.Pos).discovered)
+					}
+				}
+				chorded++
+				break search
+			}
+		}
+	}
+	a.Equal(10, chorded)
+}
+
+func TestInfiniteClone(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewInfiniteGame(40, WithSeed(1))
+	a.NoError(err)
+	game.Uncover(8, 8)
+	game.Flag(0, 0)
+
+	clone := game.Clone()
+	a.Equal(game.(*InfiniteGame).field, clone.(*InfiniteGame).field)
+	a.Equal(game.(*InfiniteGame).seed, clone.(*InfiniteGame).seed)
+
+	// Changing the clone shouldn't change the original
+	appearance := game.Appearance(-32, -32, 64, 64)
+	clone.Flag(1, 1)
+	clone.Uncover(-8, -8)
+	a.Equal(appearance, game.Appearance(-32, -32, 64, 64))
+
+	// Both should generate the same chunks
+	game.Uncover(-8, -8)
+	game.Flag(1, 1)
+	a.Equal(game.(*InfiniteGame).field, clone.(*InfiniteGame).field)
+}
+
+func TestInfiniteScore(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewInfiniteGame(40, WithSeed(1))
+	a.NoError(err)
+	g := game.(*InfiniteGame)
+	a.Equal(0, g.Score())
+
+	// Every uncovered tile is worth a point
+	g.Uncover(8, 8)
+	revealed := 0
+	for _, c := range g.field {
+		revealed += c.discovered.count()
+	}
+	a.Equal(revealed*ScoreTileRevealed, g.Score())
+
+	// Flags don't score anything until the chunk is resolved
+	var mines, safe []Pos
+	for y := 0; y < ChunkSize; y++ {
+		for x := 0; x < ChunkSize; x++ {
+			if g.field[Pos{}].tile(Pos{x, y}).mine {
+				mines = append(mines, Pos{x, y})
+			} else {
+				safe = append(safe, Pos{x, y})
+			}
+		}
+	}
+	for _, mine := range mines[1:] {
+		g.Flag(mine.X, mine.Y)
+	}
+	a.Equal(revealed*ScoreTileRevealed, g.Score())
+
+	// Hitting a mine costs points
+	score := g.Score()
+	g.Uncover(mines[0].X, mines[0].Y)
+	a.Equal(score+ScoreMineHit, g.Score())
+
+	// Uncovering every safe tile in the chunk scores the flags
+	score = g.Score()
+	for _, pos := range safe {
+		g.Uncover(pos.X, pos.Y)
+	}
+	a.Greater(g.Score(), score+(len(mines)-1)*ScoreMineFlagged)
+	a.Equal(g.field[Pos{}].score(), g.chunkScores[Pos{}])
+
+	// Undoing a move takes the points away again
+	score = g.Score()
+	a.True(g.Undo())
+	a.Less(g.Score(), score)
+	a.True(g.Redo())
+	a.Equal(score, g.Score())
+
+	// The score should survive being saved and cloned
+	var buf bytes.Buffer
+	a.NoError(g.Save(&buf))
+	loadedGame, err := Load(&buf)
+	a.NoError(err)
+	a.Equal(score, loadedGame.(*InfiniteGame).Score())
+	a.Equal(score, g.Clone().(*InfiniteGame).Score())
+}
+
+// playAtChunkBorders uncovers and flags tiles next to the borders of the
+// chunks, so the numbers depend on the chunks around them
+func playAtChunkBorders(game Game) {
+	for _, p := range []Pos{{8, 8}, {ChunkSize - 1, 3}, {-1, ChunkSize},
+		{3*ChunkSize - 1, -ChunkSize}} {
+		game.Uncover(p.X, p.Y)
+		game.Flag(p.X+1, p.Y+1)
+	}
+}
+
+func TestInfiniteAppearanceReadOnly(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 5; seed++ {
+		store := newMemoryChunkStore()
+		for _, opts := range [][]Option{
+			{WithSeed(seed)},
+			{WithSeed(seed), WithChunkCache(2, store)},
+		} {
+			game, err := NewInfiniteGame(40, opts...)
+			a.NoError(err)
+			g := game.(*InfiniteGame)
+			playAtChunkBorders(g)
+
+			var before bytes.Buffer
+			a.NoError(g.Save(&before))
+			chunks := len(g.field)
+			stored := len(store)
+
+			// Looking at the game shouldn't change what's saved, or create
+			// chunks
+			for i := 0; i < 3; i++ {
+				g.Appearance(-4*ChunkSize, -4*ChunkSize, 8*ChunkSize, 8*ChunkSize)
+				g.Appearance(ChunkSize-2, -2, 4, 4)
+				g.Appearance(-ChunkSize, 0, 1, 1)
+			}
+			var after bytes.Buffer
+			a.NoError(g.Save(&after))
+			a.Equal(before.Bytes(), after.Bytes())
+			a.Len(g.field, chunks)
+			a.Len(store, stored)
+		}
+	}
+}
+
+func TestInfiniteAppearanceNumbers(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 5; seed++ {
+		game, err := NewInfiniteGame(40, WithSeed(seed))
+		a.NoError(err)
+		playAtChunkBorders(game)
+
+		// The numbers should be the same as counting the mines, including
+		// after they're worked out again when the game is loaded
+		var buf bytes.Buffer
+		a.NoError(game.Save(&buf))
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+
+		counted := game.Clone().(*InfiniteGame)
+		for _, g := range []Game{game, loadedGame} {
+			appearance := g.Appearance(-4*ChunkSize, -4*ChunkSize,
+				8*ChunkSize, 8*ChunkSize)
+			for pos, tileType := range appearance {
+				if _, ok := tileType.Number(); !ok {
+					continue
+				}
+				number := mineCount(counted.neighbouringTiles(pos.X, pos.Y))
+				a.Equal(TileTypeNumber(number), tileType)
+			}
+		}
+	}
+}
+
+func TestInfiniteNumbers(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 20; seed++ {
+		// Bigger openings spread into more chunks
+		opts := []Option{WithSeed(seed)}
+		if seed%2 != 0 {
+			opts = append(opts, WithMinOpening(100))
+		}
+		game, err := NewInfiniteGame(40, opts...)
+		a.NoError(err)
+		g := game.(*InfiniteGame)
+
+		// Flagging creates the first move's chunk and the chunk next to it
+		// before they have their safe area
+		g.Flag(-1, 8)
+		g.Flag(9, 9)
+		g.Uncover(8, 8)
+		g.Uncover(3*ChunkSize, -ChunkSize)
+		g.Uncover(-ChunkSize, 2*ChunkSize)
+
+		// The numbers should be the same as counting the mines
+		for chunkIndex, c := range g.field {
+			for y := 0; y < ChunkSize; y++ {
+				for x := 0; x < ChunkSize; x++ {
+					pos := Pos{chunkIndex.X*ChunkSize + x, chunkIndex.Y*ChunkSize + y}
+					number := mineCount(g.neighbouringTiles(pos.X, pos.Y))
+					a.Equal(number, int(c.tile(Pos{x, y}).number))
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkInfiniteAppearance(b *testing.B) {
+	game, err := NewInfiniteGame(40, WithSeed(1))
+	if err != nil {
+		b.Fatal(err)
+	}
+	// Uncover tiles all over the viewport
+	for y := -100; y < 100; y += 5 {
+		for x := -100; x < 100; x += 5 {
+			game.Uncover(x, y)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		game.Appearance(-100, -100, 200, 200)
+	}
+}
+
+func BenchmarkRandomMines(b *testing.B) {
+	rng := rand.New(&splitMixSource{1})
+	for i := 0; i < b.N; i++ {
+		randomMines(rng, chunkTiles-9, nil)
+	}
+}
@@ -0,0 +1,545 @@
+// Package logic deduces which hidden tiles are certainly safe or certainly
+// mines from the numbers next to them. It's shared by the solver package and
+// the no-guess check in the minesweeper package, which can't import the
+// solver package
+package logic
+
+import "sort"
+
+// Pos is the position of a tile. It converts to and from minesweeper.Pos
+type Pos struct {
+	X, Y int
+}
+
+// Reason is the rule a deduction was made with
+type Reason int
+
+const (
+	// ReasonSinglePoint is for deductions from a single number, which either
+	// already has all of its mines or needs every hidden neighbour to be a mine
+	ReasonSinglePoint = Reason(iota)
+
+	// ReasonSubset is for deductions from two numbers, where the hidden
+	// neighbours of one are a subset of the other's
+	ReasonSubset
+
+	// ReasonEnumeration is for deductions from trying every arrangement of
+	// mines around a group of numbers (and the number of remaining mines, if
+	// it's known)
+	ReasonEnumeration
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonSinglePoint:
+		return "single point"
+	case ReasonSubset:
+		return "subset"
+	case ReasonEnumeration:
+		return "enumeration"
+	default:
+		return "unknown"
+	}
+}
+
+// Deduction is a tile that is certainly safe or certainly a mine
+type Deduction struct {
+	Pos
+
+	// Whether the tile is a mine (otherwise it's safe)
+	Mine bool
+
+	// The rule used to make the deduction
+	Reason Reason
+
+	// The positions of the numbers the deduction was made from
+	From []Pos
+}
+
+// MaxEnumerationTiles is the maximum number of hidden tiles in a component
+// that will be enumerated
+const MaxEnumerationTiles = 24
+
+// Constraint is the knowledge from a single number: exactly Mines of the
+// Tiles are mines
+type Constraint struct {
+	From  Pos
+	Tiles []Pos
+	Mines int
+}
+
+// Board is what's known about the tiles for a single step
+type Board struct {
+	// The constraints from the numbers next to unknown tiles
+	Constraints []Constraint
+
+	// Every unknown tile
+	Unknown map[Pos]bool
+
+	// The number of mines in the unknown tiles, or -1 if it isn't known
+	RemainingMines int
+}
+
+// Step makes every deduction it can using the simplest rule that finds
+// anything, and returns them sorted by position. Returns nil if nothing can be
+// deduced without guessing
+func (b *Board) Step() []Deduction {
+	// Sort the constraints so the deductions are deterministic
+	sort.Slice(b.Constraints, func(i, j int) bool {
+		return lessPos(b.Constraints[i].From, b.Constraints[j].From)
+	})
+
+	for _, rule := range []func(*Board) []Deduction{
+		singlePoint, subset, enumeration} {
+		deductions := rule(b)
+		if len(deductions) > 0 {
+			sort.Slice(deductions, func(i, j int) bool {
+				return lessPos(deductions[i].Pos, deductions[j].Pos)
+			})
+			return deductions
+		}
+	}
+	return nil
+}
+
+// Simplify repeatedly makes every deduction it can with the single point and
+// subset rules, taking the deduced tiles out of the board (along with their
+// mines, from the constraints and the remaining mines) until nothing else can
+// be deduced. The deductions are returned sorted by position
+func (b *Board) Simplify() []Deduction {
+	var all []Deduction
+	for {
+		sort.Slice(b.Constraints, func(i, j int) bool {
+			return lessPos(b.Constraints[i].From, b.Constraints[j].From)
+		})
+		deductions := singlePoint(b)
+		if len(deductions) == 0 {
+			deductions = subset(b)
+		}
+		if len(deductions) == 0 {
+			break
+		}
+		b.remove(deductions)
+		all = append(all, deductions...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return lessPos(all[i].Pos, all[j].Pos)
+	})
+	return all
+}
+
+// remove takes the deduced tiles out of the board
+func (b *Board) remove(deductions []Deduction) {
+	mines := make(map[Pos]bool, len(deductions))
+	for _, d := range deductions {
+		mines[d.Pos] = d.Mine
+		delete(b.Unknown, d.Pos)
+		if d.Mine && b.RemainingMines > 0 {
+			b.RemainingMines--
+		}
+	}
+
+	constraints := make([]Constraint, 0, len(b.Constraints))
+	for _, c := range b.Constraints {
+		var tiles []Pos
+		for _, pos := range c.Tiles {
+			mine, ok := mines[pos]
+			if !ok {
+				tiles = append(tiles, pos)
+			} else if mine {
+				c.Mines--
+			}
+		}
+		if len(tiles) > 0 {
+			c.Tiles = tiles
+			constraints = append(constraints, c)
+		}
+	}
+	b.Constraints = constraints
+}
+
+func lessPos(a, b Pos) bool {
+	if a.Y != b.Y {
+		return a.Y < b.Y
+	}
+	return a.X < b.X
+}
+
+// deductions collects deductions, ignoring duplicates
+type deductions struct {
+	list []Deduction
+	seen map[Pos]bool
+}
+
+func (d *deductions) add(p Pos, mine bool, reason Reason, from []Pos) {
+	if d.seen == nil {
+		d.seen = make(map[Pos]bool)
+	}
+	if d.seen[p] {
+		return
+	}
+	d.seen[p] = true
+	d.list = append(d.list, Deduction{Pos: p, Mine: mine, Reason: reason, From: from})
+}
+
+// deduce adds the deductions that can be made from a single constraint
+func (d *deductions) deduce(c Constraint, reason Reason, from []Pos) {
+	if c.Mines != 0 && c.Mines != len(c.Tiles) {
+		return
+	}
+	for _, pos := range c.Tiles {
+		d.add(pos, c.Mines != 0, reason, from)
+	}
+}
+
+func singlePoint(b *Board) []Deduction {
+	var d deductions
+	for _, c := range b.Constraints {
+		d.deduce(c, ReasonSinglePoint, []Pos{c.From})
+	}
+	return d.list
+}
+
+// byTile indexes the constraints by the tiles in them
+func (b *Board) byTile() map[Pos][]int {
+	byTile := make(map[Pos][]int)
+	for i, c := range b.Constraints {
+		for _, pos := range c.Tiles {
+			byTile[pos] = append(byTile[pos], i)
+		}
+	}
+	return byTile
+}
+
+func subset(b *Board) []Deduction {
+	byTile := b.byTile()
+
+	var d deductions
+	for i, a := range b.Constraints {
+		// Only compare against constraints that share a tile
+		compared := make(map[int]bool)
+		for _, pos := range a.Tiles {
+			for _, j := range byTile[pos] {
+				if i == j || compared[j] {
+					continue
+				}
+				compared[j] = true
+				c := b.Constraints[j]
+				diff, ok := difference(c, a)
+				if ok {
+					d.deduce(diff, ReasonSubset, []Pos{a.From, c.From})
+				}
+			}
+		}
+	}
+	return d.list
+}
+
+// difference returns the constraint for the tiles in a that aren't in b, if
+// b's tiles are a strict subset of a's
+func difference(a, b Constraint) (Constraint, bool) {
+	if len(b.Tiles) >= len(a.Tiles) {
+		return Constraint{}, false
+	}
+	inB := make(map[Pos]bool, len(b.Tiles))
+	for _, pos := range b.Tiles {
+		inB[pos] = true
+	}
+	diff := Constraint{Mines: a.Mines - b.Mines}
+	for _, pos := range a.Tiles {
+		if !inB[pos] {
+			diff.Tiles = append(diff.Tiles, pos)
+		}
+	}
+	if len(diff.Tiles) != len(a.Tiles)-len(b.Tiles) {
+		return Constraint{}, false
+	}
+	return diff, true
+}
+
+func enumeration(b *Board) []Deduction {
+	components, interior := b.Components()
+
+	// Find the possible mine counts of each component
+	sums := make([]map[int]bool, len(components))
+	for i, comp := range components {
+		sums[i] = make(map[int]bool)
+		if comp.Solutions == nil {
+			// Too big to enumerate, so assume any number of mines
+			for k := 0; k <= len(comp.Tiles); k++ {
+				sums[i][k] = true
+			}
+		} else {
+			for k := range comp.Solutions {
+				sums[i][k] = true
+			}
+		}
+	}
+
+	// feasible returns whether the mine counts in the given set can be made
+	// up to the number of remaining mines, with the interior taking the rest
+	feasible := func(exclude int, k int) bool {
+		if b.RemainingMines < 0 {
+			return true
+		}
+		for total := range possibleSums(sums, exclude) {
+			rest := b.RemainingMines - k - total
+			if rest >= 0 && rest <= len(interior) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var d deductions
+	for i, comp := range components {
+		if comp.Solutions == nil {
+			continue
+		}
+		var from []Pos
+		for _, c := range comp.Constraints {
+			from = append(from, c.From)
+		}
+		for t, pos := range comp.Tiles {
+			alwaysMine, neverMine := true, true
+			for k, sol := range comp.Solutions {
+				if !feasible(i, k) {
+					continue
+				}
+				if sol.Mines[t] != sol.Count {
+					alwaysMine = false
+				}
+				if sol.Mines[t] != 0 {
+					neverMine = false
+				}
+			}
+			if alwaysMine || neverMine {
+				d.add(pos, alwaysMine, ReasonEnumeration, from)
+			}
+		}
+	}
+
+	// If the number of remaining mines is known, the interior tiles can be
+	// deduced when the components leave it no choice
+	if b.RemainingMines >= 0 && len(interior) > 0 {
+		allSafe, allMines := true, true
+		for total := range possibleSums(sums, -1) {
+			rest := b.RemainingMines - total
+			if rest < 0 || rest > len(interior) {
+				continue
+			}
+			if rest != 0 {
+				allSafe = false
+			}
+			if rest != len(interior) {
+				allMines = false
+			}
+		}
+		if allSafe || allMines {
+			for _, pos := range interior {
+				d.add(pos, allMines, ReasonEnumeration, nil)
+			}
+		}
+	}
+	return d.list
+}
+
+// possibleSums returns every total number of mines the components could
+// have, excluding the component at the given index
+func possibleSums(sums []map[int]bool, exclude int) map[int]bool {
+	totals := map[int]bool{0: true}
+	for i, s := range sums {
+		if i == exclude {
+			continue
+		}
+		next := make(map[int]bool)
+		for total := range totals {
+			for k := range s {
+				next[total+k] = true
+			}
+		}
+		totals = next
+	}
+	return totals
+}
+
+// Solutions stores the arrangements of mines in a component that have a
+// particular number of mines
+type Solutions struct {
+	// The number of arrangements
+	Count float64
+	// The number of arrangements where each tile is a mine
+	Mines []float64
+}
+
+// Component is a group of unknown tiles connected by constraints
+type Component struct {
+	Tiles       []Pos
+	Constraints []Constraint
+
+	// The arrangements of mines, by number of mines. nil if the component
+	// has more than MaxEnumerationTiles tiles
+	Solutions map[int]*Solutions
+}
+
+// Components splits the unknown tiles next to a number into independent
+// components, and returns them along with the unknown tiles that aren't next
+// to any number
+func (b *Board) Components() (components []*Component, interior []Pos) {
+	byTile := b.byTile()
+
+	// Flood fill through the constraints to find the components
+	visited := make([]bool, len(b.Constraints))
+	for i := range b.Constraints {
+		if visited[i] {
+			continue
+		}
+		comp := new(Component)
+		inComp := make(map[Pos]bool)
+		queue := []int{i}
+		visited[i] = true
+		for len(queue) > 0 {
+			c := b.Constraints[queue[0]]
+			queue = queue[1:]
+			comp.Constraints = append(comp.Constraints, c)
+			for _, pos := range c.Tiles {
+				if inComp[pos] {
+					continue
+				}
+				inComp[pos] = true
+				comp.Tiles = append(comp.Tiles, pos)
+				for _, j := range byTile[pos] {
+					if !visited[j] {
+						visited[j] = true
+						queue = append(queue, j)
+					}
+				}
+			}
+		}
+		if len(comp.Tiles) <= MaxEnumerationTiles {
+			comp.enumerate()
+		}
+		components = append(components, comp)
+	}
+
+	for pos := range b.Unknown {
+		if len(byTile[pos]) == 0 {
+			interior = append(interior, pos)
+		}
+	}
+	sort.Slice(interior, func(i, j int) bool {
+		return lessPos(interior[i], interior[j])
+	})
+	return
+}
+
+// enumerate finds every arrangement of mines that satisfies the component's
+// constraints
+func (comp *Component) enumerate() {
+	comp.Solutions = make(map[int]*Solutions)
+	comp.arrangements(func(assignment []bool, numMines int) {
+		sol, ok := comp.Solutions[numMines]
+		if !ok {
+			sol = &Solutions{Mines: make([]float64, len(comp.Tiles))}
+			comp.Solutions[numMines] = sol
+		}
+		sol.Count++
+		for i, mine := range assignment {
+			if mine {
+				sol.Mines[i]++
+			}
+		}
+	})
+}
+
+// Weighted returns the total weight of the arrangements of mines that satisfy
+// the component's constraints, and the weight of those where each tile is a
+// mine. Each tile is independently a mine with the probability given by
+// prior, so an arrangement's weight is the chance of it happening
+func (comp *Component) Weighted(prior func(Pos) float64) (total float64,
+	mines []float64) {
+	priors := make([]float64, len(comp.Tiles))
+	for i, pos := range comp.Tiles {
+		priors[i] = prior(pos)
+	}
+	mines = make([]float64, len(comp.Tiles))
+	comp.arrangements(func(assignment []bool, _ int) {
+		w := 1.0
+		for i, mine := range assignment {
+			if mine {
+				w *= priors[i]
+			} else {
+				w *= 1 - priors[i]
+			}
+		}
+		total += w
+		for i, mine := range assignment {
+			if mine {
+				mines[i] += w
+			}
+		}
+	})
+	return
+}
+
+// arrangements calls visit with every arrangement of mines that satisfies the
+// component's constraints, and the number of mines in it
+func (comp *Component) arrangements(visit func(assignment []bool,
+	numMines int)) {
+	index := make(map[Pos]int, len(comp.Tiles))
+	for i, pos := range comp.Tiles {
+		index[pos] = i
+	}
+	// The constraints on each tile
+	tileConstraints := make([][]int, len(comp.Tiles))
+	// The number of mines each constraint still needs, and the number of its
+	// tiles that haven't been assigned yet
+	needed := make([]int, len(comp.Constraints))
+	unassigned := make([]int, len(comp.Constraints))
+	for i, c := range comp.Constraints {
+		needed[i] = c.Mines
+		unassigned[i] = len(c.Tiles)
+		for _, pos := range c.Tiles {
+			t := index[pos]
+			tileConstraints[t] = append(tileConstraints[t], i)
+		}
+	}
+
+	assignment := make([]bool, len(comp.Tiles))
+	var assign func(t, numMines int)
+	assign = func(t, numMines int) {
+		if t == len(comp.Tiles) {
+			visit(assignment, numMines)
+			return
+		}
+
+		for _, mine := range []bool{false, true} {
+			ok := true
+			for _, c := range tileConstraints[t] {
+				unassigned[c]--
+				if mine {
+					needed[c]--
+				}
+				if needed[c] < 0 || needed[c] > unassigned[c] {
+					ok = false
+				}
+			}
+			if ok {
+				assignment[t] = mine
+				k := numMines
+				if mine {
+					k++
+				}
+				assign(t+1, k)
+			}
+			for _, c := range tileConstraints[t] {
+				unassigned[c]++
+				if mine {
+					needed[c]++
+				}
+			}
+		}
+		assignment[t] = false
+	}
+	assign(0, 0)
+}
@@ -0,0 +1,43 @@
+package minesweeper
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithTimeLimit ends an infinite game once the given amount of time has
+// passed since it started. When the time runs out the game is won, unless it
+// has a target score it hasn't reached (see WithTargetScore). The game's state
+// changes as soon as the time runs out. A SyncGame emits the StateChangedEvent
+// then too, but other games can't be changed from another goroutine, so they
+// emit it with the next move. To end the game after a number of mines have
+// been hit, see WithLives
+func WithTimeLimit(limit time.Duration) Option {
+	return func(o *options) {
+		o.timeLimit = limit
+	}
+}
+
+// WithTargetScore makes an infinite game won as soon as its score reaches the
+// given target, see InfiniteGame.Score
+func WithTargetScore(score int) Option {
+	return func(o *options) {
+		o.targetScore = score
+	}
+}
+
+// validateLimits returns an error if the options' limits are invalid for a
+// game, given whether it's infinite
+func (o options) validateLimits(infinite bool) error {
+	if !infinite && (o.timeLimit != 0 || o.targetScore != 0) {
+		return fmt.Errorf(
+			"time limits and target scores can only be used with infinite games")
+	}
+	if o.timeLimit < 0 {
+		return fmt.Errorf("invalid time limit %s", o.timeLimit)
+	}
+	if o.targetScore < 0 {
+		return fmt.Errorf("invalid target score %d", o.targetScore)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestInfiniteTimeLimit(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewInfiniteGame(40, WithSeed(1), WithTimeLimit(time.Minute))
+	a.NoError(err)
+	g := game.(*InfiniteGame)
+	a.Equal(time.Minute, g.TimeLimit())
+	g.Uncover(8, 8)
+	a.Equal(GameStatePlaying, g.State())
+
+	// Pretend the game started 2 minutes ago
+	g.startTime = g.startTime.Add(-2 * time.Minute)
+
+	// The game is over, and the time stops at the limit
+	a.Equal(GameStateWin, g.State())
+	a.Equal(time.Minute, g.SinceStart())
+
+	// Moves don't do anything, other than emit the change in state
+	var events []Event
+	g.Subscribe(func(e Event) {
+		events = append(events, e)
+	})
+	score := g.Score()
+	a.Equal(GameStateWin, g.Uncover(100, 100))
+	a.Equal(score, g.Score())
+	a.Equal([]Event{StateChangedEvent{GameStatePlaying, GameStateWin}}, events)
+	a.Equal(time.Minute, g.SinceStart())
+
+	// The end of the game should survive being saved
+	var buf bytes.Buffer
+	a.NoError(g.Save(&buf))
+	loadedGame, err := Load(&buf)
+	a.NoError(err)
+	a.Equal(GameStateWin, loadedGame.State())
+	a.Equal(time.Minute, loadedGame.SinceStart())
+	a.Equal(time.Minute, loadedGame.(*InfiniteGame).TimeLimit())
+}
+
+func TestSyncGameTimeLimit(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewInfiniteGame(40, WithSeed(1),
+		WithTimeLimit(50*time.Millisecond))
+	a.NoError(err)
+	g := NewSyncGame(game)
+	events := make(chan Event, 16)
+	g.Subscribe(func(e Event) {
+		events <- e
+	})
+	g.Uncover(8, 8)
+
+	// The change in state is emitted when the time runs out, without another
+	// move
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e == (StateChangedEvent{GameStatePlaying, GameStateWin}) {
+				a.Equal(GameStateWin, g.State())
+				a.Equal(50*time.Millisecond, g.SinceStart())
+				return
+			}
+		case <-timeout:
+			a.Fail("the time limit ran out without an event")
+			return
+		}
+	}
+}
+
+func TestInfiniteTargetScore(t *testing.T) {
+	a := assert.New(t)
+
+	// Reaching the target score wins the game
+	game, err := NewInfiniteGame(40, WithSeed(1), WithTargetScore(10))
+	a.NoError(err)
+	g := game.(*InfiniteGame)
+	a.Equal(10, g.TargetScore())
+	a.Equal(GameStateWin, g.Uncover(8, 8))
+	a.GreaterOrEqual(g.Score(), 10)
+
+	// And the time stops
+	since := g.SinceStart()
+	time.Sleep(time.Millisecond)
+	a.Equal(since, g.SinceStart())
+
+	// Undoing the move takes the game back to before it ended
+	a.True(g.Undo())
+	a.Equal(GameStatePlaying, g.State())
+
+	// Running out of time before reaching the target loses the game
+	game, err = NewInfiniteGame(40, WithSeed(1), WithTargetScore(10000),
+		WithTimeLimit(time.Minute))
+	a.NoError(err)
+	g = game.(*InfiniteGame)
+	g.Uncover(8, 8)
+	g.startTime = g.startTime.Add(-2 * time.Minute)
+	a.Equal(GameStateLoss, g.State())
+
+	// Flagging doesn't do anything either
+	g.Flag(100, 100)
+	a.False(g.get(Pos{100, 100}).flagged)
+	a.Equal(GameStateLoss, g.state)
+}
+
+func TestLimitsOptions(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := NewInfiniteGame(40, WithTimeLimit(-time.Second))
+	a.Error(err)
+	_, err = NewInfiniteGame(40, WithTargetScore(-1))
+	a.Error(err)
+
+	// Finite games end when they're cleared
+	_, err = NewGame(16, 16, 40, WithTimeLimit(time.Minute))
+	a.Error(err)
+	_, err = NewGame(16, 16, 40, WithTargetScore(100))
+	a.Error(err)
+}
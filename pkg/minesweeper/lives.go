@@ -0,0 +1,30 @@
+package minesweeper
+
+import "fmt"
+
+// UnlimitedLives is the number of lives in a game where uncovering a mine
+// never loses the game, see WithLives
+const UnlimitedLives = -1
+
+// WithLives sets the number of lives a game starts with (or UnlimitedLives).
+// Uncovering a mine costs a life and shows the mine as TileTypeExploded, and
+// the game is only lost when there are no lives left. By default finite games
+// have 1 life, and infinite games have unlimited lives
+func WithLives(lives int) Option {
+	return func(o *options) {
+		o.lives = lives
+	}
+}
+
+// startingLives returns the number of lives a game starts with, given the
+// number it has by default
+func (o options) startingLives(defaultLives int) (int, error) {
+	switch {
+	case o.lives == 0:
+		return defaultLives, nil
+	case o.lives < 0 && o.lives != UnlimitedLives:
+		return 0, fmt.Errorf("invalid number of lives %d", o.lives)
+	default:
+		return o.lives, nil
+	}
+}
@@ -0,0 +1,168 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// findMines returns the positions of the mines in the finite game
+func findMines(g *FiniteGame) (mines []Pos) {
+	for y, row := range g.field {
+		for x, tile := range row {
+			if tile.Type == TileTypeMine {
+				mines = append(mines, Pos{x, y})
+			}
+		}
+	}
+	return
+}
+
+func TestFiniteLives(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame(16, 16, 40, WithSeed(1), WithLives(3))
+	a.NoError(err)
+	g := game.(*FiniteGame)
+	a.Equal(3, g.Lives())
+	g.Uncover(8, 8)
+	mines := findMines(g)
+
+	// Each mine costs a life, and is shown as exploded
+	for lives := 2; lives > 0; lives-- {
+		mine := mines[2-lives]
+		a.Equal(GameStatePlaying, g.Uncover(mine.X, mine.Y))
+		a.Equal(lives, g.Lives())
+		a.Equal(TileTypeExploded, g.Appearance(mine.X, mine.Y, 1, 1)[mine])
+	}
+	// The exploded mines have been found
+	a.Equal(float64(38), g.RemainingMines())
+	// The other mines are still hidden
+	a.Equal(TileTypeHidden, g.Appearance(mines[2].X, mines[2].Y, 1, 1)[mines[2]])
+
+	// Undoing a move gives the life back
+	a.True(g.Undo())
+	a.Equal(2, g.Lives())
+	a.Equal(TileTypeHidden, g.Appearance(mines[1].X, mines[1].Y, 1, 1)[mines[1]])
+	a.True(g.Redo())
+	a.Equal(1, g.Lives())
+
+	// The lives should survive being saved
+	var buf bytes.Buffer
+	a.NoError(g.Save(&buf))
+	loadedGame, err := Load(&buf)
+	a.NoError(err)
+	a.Equal(1, loadedGame.Lives())
+	a.Equal(g.RemainingMines(), loadedGame.RemainingMines())
+
+	// Running out of lives loses the game, and reveals the mines
+	a.Equal(GameStateLoss, g.Uncover(mines[2].X, mines[2].Y))
+	a.Equal(0, g.Lives())
+	a.Equal(TileTypeExploded, g.Appearance(mines[2].X, mines[2].Y, 1, 1)[mines[2]])
+	a.Equal(TileTypeMine, g.Appearance(mines[3].X, mines[3].Y, 1, 1)[mines[3]])
+
+	// Resetting the game restores the lives
+	a.NoError(g.Reset(40))
+	a.Equal(3, g.Lives())
+	a.Equal(float64(40), g.RemainingMines())
+}
+
+func TestFiniteLivesDefault(t *testing.T) {
+	a := assert.New(t)
+
+	// By default, the first mine loses the game
+	game, err := NewGame(16, 16, 40, WithSeed(1))
+	a.NoError(err)
+	g := game.(*FiniteGame)
+	a.Equal(1, g.Lives())
+	g.Uncover(8, 8)
+	mine := findMines(g)[0]
+	a.Equal(GameStateLoss, g.Uncover(mine.X, mine.Y))
+	a.Equal(0, g.Lives())
+
+	// Unless the lives are unlimited
+	game, err = NewGame(16, 16, 40, WithSeed(1), WithLives(UnlimitedLives))
+	a.NoError(err)
+	g = game.(*FiniteGame)
+	g.Uncover(8, 8)
+	for _, mine := range findMines(g) {
+		a.Equal(GameStatePlaying, g.Uncover(mine.X, mine.Y))
+	}
+	a.Equal(UnlimitedLives, g.Lives())
+	a.Equal(float64(0), g.RemainingMines())
+
+	_, err = NewGame(16, 16, 40, WithLives(-2))
+	a.Error(err)
+	_, err = NewInfiniteGame(40, WithLives(-2))
+	a.Error(err)
+}
+
+func TestFiniteLivesChord(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewGame(16, 16, 40, WithSeed(seed), WithLives(2))
+		a.NoError(err)
+		g := game.(*FiniteGame)
+		g.Uncover(8, 8)
+
+		pos, mines, safe, found := findChordable(g)
+		if !found {
+			continue
+		}
+
+		// An exploded mine counts as a flag
+		a.Equal(GameStatePlaying, g.Uncover(mines[0].X, mines[0].Y))
+		for _, mine := range mines[1:] {
+			g.Flag(mine.X, mine.Y)
+		}
+		a.Equal(GameStatePlaying, g.Chord(pos.X, pos.Y))
+		for _, p := range safe {
+			a.True(g.field[p.Y][p.X].Discovered)
+		}
+	}
+}
+
+func TestInfiniteLives(t *testing.T) {
+	a := assert.New(t)
+
+	// By default, infinite games carry on forever
+	game, err := NewInfiniteGame(40, WithSeed(1))
+	a.NoError(err)
+	a.Equal(UnlimitedLives, game.Lives())
+
+	game, err = NewInfiniteGame(40, WithSeed(1), WithLives(2))
+	a.NoError(err)
+	g := game.(*InfiniteGame)
+	g.Uncover(8, 8)
+
+	// Find some mines
+	var mines []Pos
+	for y := 0; y < ChunkSize && len(mines) < 2; y++ {
+		for x := 0; x < ChunkSize && len(mines) < 2; x++ {
+			if g.get(Pos{x, y}).mine {
+				mines = append(mines, Pos{x, y})
+			}
+		}
+	}
+	a.Len(mines, 2)
+
+	a.Equal(GameStatePlaying, g.Uncover(mines[0].X, mines[0].Y))
+	a.Equal(1, g.Lives())
+	a.Equal(TileTypeExploded, g.Appearance(mines[0].X, mines[0].Y, 1, 1)[mines[0]])
+
+	// The lives should survive being saved
+	var buf bytes.Buffer
+	a.NoError(g.Save(&buf))
+	loadedGame, err := Load(&buf)
+	a.NoError(err)
+	a.Equal(1, loadedGame.Lives())
+
+	a.Equal(GameStateLoss, g.Uncover(mines[1].X, mines[1].Y))
+	a.Equal(0, g.Lives())
+
+	// Undoing the move gives the life back
+	a.True(g.Undo())
+	a.Equal(GameStatePlaying, g.State())
+	a.Equal(1, g.Lives())
+}
@@ -0,0 +1,126 @@
+package minesweeper
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Neighbourhood is the offsets of the tiles that are next to a tile. The
+// numbers count the mines in a tile's neighbourhood, and uncovering an empty
+// tile uncovers its neighbourhood
+type Neighbourhood []Pos
+
+var (
+	// NeighbourhoodMoore is the usual neighbourhood, the 8 tiles around a tile
+	NeighbourhoodMoore = NeighbourhoodSquare(1)
+
+	// NeighbourhoodCross is the 4 tiles above, below, left and right of a
+	// tile
+	NeighbourhoodCross = Neighbourhood{{0, -1}, {-1, 0}, {1, 0}, {0, 1}}
+
+	// NeighbourhoodKnight is the 8 tiles a knight's move away from a tile
+	NeighbourhoodKnight = Neighbourhood{
+		{-1, -2}, {1, -2},
+		{-2, -1}, {2, -1},
+		{-2, 1}, {2, 1},
+		{-1, 2}, {1, 2},
+	}
+)
+
+// NeighbourhoodSquare returns the tiles in the square of the given radius
+// around a tile, so a radius of 1 is NeighbourhoodMoore and a radius of 2 is
+// the 24 tiles in the 5x5 around a tile
+func NeighbourhoodSquare(radius int) Neighbourhood {
+	n := make(Neighbourhood, 0, (2*radius+1)*(2*radius+1)-1)
+	for y := -radius; y <= radius; y++ {
+		for x := -radius; x <= radius; x++ {
+			if x != 0 || y != 0 {
+				n = append(n, Pos{x, y})
+			}
+		}
+	}
+	return n
+}
+
+// WithNeighbourhood sets which tiles are next to each other in a game with a
+// grid of squares, the default is NeighbourhoodMoore. The first move and its
+// neighbourhood never have mines, so bigger neighbourhoods allow fewer mines
+func WithNeighbourhood(n Neighbourhood) Option {
+	return func(o *options) {
+		o.neighbourhood = n
+	}
+}
+
+// validate returns an error if the neighbourhood can't be used in a game
+func (n Neighbourhood) validate() error {
+	if len(n) == 0 {
+		return fmt.Errorf("a neighbourhood needs at least one tile")
+	}
+	// The number of neighbours has to fit into a serialised tile type
+	if TileTypeNumber(len(n)) > math.MaxUint8 {
+		return fmt.Errorf("too many tiles in the neighbourhood (%d)", len(n))
+	}
+	for i, offset := range n {
+		if offset == (Pos{}) {
+			return fmt.Errorf("a tile can't be in its own neighbourhood")
+		}
+		if containsPos(n[:i], offset) {
+			return fmt.Errorf("%v is in the neighbourhood more than once", offset)
+		}
+		// Infinite games only look one chunk away for neighbours
+		if offset.X <= -ChunkSize || offset.X >= ChunkSize ||
+			offset.Y <= -ChunkSize || offset.Y >= ChunkSize {
+			return fmt.Errorf("%v is too far away to be in the neighbourhood",
+				offset)
+		}
+	}
+	return nil
+}
+
+// symmetric returns whether every tile is in the neighbourhood of the tiles in
+// its own neighbourhood, which is true for the default (nil) neighbourhood
+func (n Neighbourhood) symmetric() bool {
+	for _, offset := range n {
+		if !containsPos(n, Pos{-offset.X, -offset.Y}) {
+			return false
+		}
+	}
+	return true
+}
+
+// saveNeighbourhood writes the neighbourhood, where nil is the game's default
+func saveNeighbourhood(w io.Writer, n Neighbourhood) error {
+	err := binary.Write(w, serialiseByteOrder, int64(len(n)))
+	if err != nil {
+		return err
+	}
+	for _, offset := range n {
+		err = binary.Write(w, serialiseByteOrder,
+			[]int64{int64(offset.X), int64(offset.Y)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadNeighbourhood(r io.Reader) (Neighbourhood, error) {
+	var length int64
+	err := binary.Read(r, serialiseByteOrder, &length)
+	if err != nil || length == 0 {
+		return nil, err
+	}
+	n := make(Neighbourhood, length)
+	for i := range n {
+		offset := make([]int64, 2)
+		err = binary.Read(r, serialiseByteOrder, offset)
+		if err != nil {
+			return nil, err
+		}
+		// todo int overflow
+		n[i] = Pos{int(offset[0]), int(offset[1])}
+	}
+	return n, n.validate()
+}
@@ -0,0 +1,180 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNeighbourhoodSquare(t *testing.T) {
+	a := assert.New(t)
+
+	a.Len(NeighbourhoodSquare(1), 8)
+	a.Len(NeighbourhoodSquare(2), 24)
+	a.NotContains(NeighbourhoodSquare(2), Pos{})
+	a.Contains(NeighbourhoodSquare(2), Pos{-2, 2})
+}
+
+func TestNeighbourhoodValidate(t *testing.T) {
+	a := assert.New(t)
+
+	for _, n := range []Neighbourhood{
+		NeighbourhoodMoore, NeighbourhoodCross, NeighbourhoodKnight,
+		NeighbourhoodSquare(2), NeighbourhoodSquare(7)} {
+		a.NoError(n.validate())
+	}
+
+	// Empty
+	a.Error(Neighbourhood{}.validate())
+	// The tile itself
+	a.Error(Neighbourhood{{1, 0}, {0, 0}}.validate())
+	// Repeated tiles
+	a.Error(Neighbourhood{{1, 0}, {1, 0}}.validate())
+	// Too far away
+	a.Error(Neighbourhood{{ChunkSize, 0}}.validate())
+	// Too many tiles to be stored
+	a.Error(NeighbourhoodSquare(8).validate())
+
+	// Games shouldn't be created with invalid neighbourhoods
+	_, err := NewGame(16, 16, 40, WithNeighbourhood(Neighbourhood{}))
+	a.Error(err)
+	_, err = NewInfiniteGame(40, WithNeighbourhood(Neighbourhood{}))
+	a.Error(err)
+
+	// Hex games have their own neighbours
+	_, err = NewHexGame(16, 16, 40, WithNeighbourhood(NeighbourhoodCross))
+	a.Error(err)
+}
+
+// countMines counts the mines in the given game at the neighbourhood's
+// offsets from the given position
+func countMines(isMine func(p Pos) bool, n Neighbourhood, p Pos) int {
+	count := 0
+	for _, offset := range n {
+		if isMine(Pos{p.X + offset.X, p.Y + offset.Y}) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestFiniteNeighbourhood(t *testing.T) {
+	a := assert.New(t)
+
+	for _, n := range []Neighbourhood{
+		NeighbourhoodCross, NeighbourhoodKnight, NeighbourhoodSquare(2)} {
+		for seed := int64(0); seed < 10; seed++ {
+			game, err := NewGame(16, 16, 100, WithSeed(seed),
+				WithNeighbourhood(n))
+			a.NoError(err)
+			g := game.(*FiniteGame)
+			g.Uncover(8, 8)
+
+			// The first move and its neighbourhood are never mines
+			a.NotEqual(TileTypeMine, g.field[8][8].Type)
+			for _, offset := range n {
+				a.NotEqual(TileTypeMine, g.field[8+offset.Y][8+offset.X].Type)
+			}
+
+			// The first move is empty, so its neighbourhood is uncovered
+			for _, offset := range n {
+				a.True(g.field[8+offset.Y][8+offset.X].Discovered)
+			}
+
+			// The numbers count the mines in the neighbourhood
+			isMine := func(p Pos) bool {
+				return p.X >= 0 && p.Y >= 0 && p.X < 16 && p.Y < 16 &&
+					g.field[p.Y][p.X].Type == TileTypeMine
+			}
+			for y, row := range g.field {
+				for x, tile := range row {
+					if tile.Type != TileTypeMine {
+						a.Equal(TileTypeNumber(countMines(isMine, n, Pos{x, y})),
+							tile.Type)
+					}
+				}
+			}
+
+			// The numbers should survive being saved, even the ones above 8
+			var buf bytes.Buffer
+			a.NoError(g.Save(&buf))
+			loadedGame, err := Load(&buf)
+			a.NoError(err)
+			a.Equal(g.field, loadedGame.(*FiniteGame).field)
+			a.Equal(g.Neighbours(0, 0), loadedGame.(*FiniteGame).Neighbours(0, 0))
+		}
+	}
+}
+
+func TestFiniteAsymmetricNeighbourhood(t *testing.T) {
+	a := assert.New(t)
+
+	// Only the tiles to the right and below are neighbours
+	n := Neighbourhood{{1, 0}, {2, 0}, {0, 1}}
+	a.False(n.symmetric())
+	a.True(NeighbourhoodKnight.symmetric())
+	a.True(Neighbourhood(nil).symmetric())
+
+	// The numbers are worked out differently with few and many mines, but
+	// they should both count the mines in the neighbourhood
+	for _, numMines := range []int{20, 200} {
+		for seed := int64(0); seed < 10; seed++ {
+			game, err := NewGame(16, 16, numMines, WithSeed(seed),
+				WithNeighbourhood(n))
+			a.NoError(err)
+			g := game.(*FiniteGame)
+			g.Uncover(8, 8)
+
+			isMine := func(p Pos) bool {
+				return p.X >= 0 && p.Y >= 0 && p.X < 16 && p.Y < 16 &&
+					g.field[p.Y][p.X].Type == TileTypeMine
+			}
+			for y, row := range g.field {
+				for x, tile := range row {
+					if tile.Type != TileTypeMine {
+						a.Equal(TileTypeNumber(countMines(isMine, n, Pos{x, y})),
+							tile.Type, "%d mines, seed %d", numMines, seed)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestInfiniteNeighbourhood(t *testing.T) {
+	a := assert.New(t)
+
+	for _, n := range []Neighbourhood{
+		NeighbourhoodCross, NeighbourhoodKnight, NeighbourhoodSquare(2)} {
+		for seed := int64(0); seed < 5; seed++ {
+			game, err := NewInfiniteGame(40, WithSeed(seed),
+				WithNeighbourhood(n))
+			a.NoError(err)
+			g := game.(*InfiniteGame)
+			g.Uncover(8, 8)
+
+			// The first move is empty, so its neighbourhood is uncovered
+			for _, offset := range n {
+				a.True(g.get(Pos{8 + offset.X, 8 + offset.Y}).discovered)
+			}
+
+			// The numbers count the mines in the neighbourhood
+			isMine := func(p Pos) bool {
+				return g.get(p).mine
+			}
+			for pos, tileType := range g.Appearance(0, 0, 16, 16) {
+				if _, ok := tileType.Number(); ok {
+					a.Equal(TileTypeNumber(countMines(isMine, n, pos)), tileType)
+				}
+			}
+
+			// The neighbourhood should be saved with the game
+			var buf bytes.Buffer
+			a.NoError(g.Save(&buf))
+			loadedGame, err := Load(&buf)
+			a.NoError(err)
+			a.Equal(g.Appearance(-8, -8, 32, 32),
+				loadedGame.Appearance(-8, -8, 32, 32))
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package minesweeper
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/bhollier/minesweeper/pkg/minesweeper/internal/logic"
+	"io"
+	"time"
+)
+
+// ErrNoGuessBudgetExhausted is returned by FiniteGame.Populate when a field
+// that can be solved without guessing couldn't be generated within the
+// budget given to WithNoGuess
+var ErrNoGuessBudgetExhausted = errors.New("no-guess budget exhausted")
+
+// DefaultNoGuessAttempts is the number of fields tried when WithNoGuess isn't
+// given a limit, as some settings never generate a no-guess field
+const DefaultNoGuessAttempts = 1000
+
+// noGuessOptions stores how no-guess fields are generated
+type noGuessOptions struct {
+	enabled bool
+	// The maximum number of fields to try, or 0 for no limit
+	maxAttempts int
+	// The maximum amount of time to spend trying, or 0 for no limit
+	timeout time.Duration
+}
+
+// WithNoGuess makes a FiniteGame only generate fields that can be fully
+// cleared from the first move with logic alone. Fields are generated until one
+// is found, or until maxAttempts fields have been tried or timeout has elapsed
+// (0 for no limit). If both are 0, DefaultNoGuessAttempts fields are tried. If
+// the budget is exhausted, Populate returns ErrNoGuessBudgetExhausted, and
+// Uncover falls back to a normal field (see FiniteGame.NoGuessErr)
+func WithNoGuess(maxAttempts int, timeout time.Duration) Option {
+	return func(o *options) {
+		o.noGuess = noGuessOptions{
+			enabled:     true,
+			maxAttempts: maxAttempts,
+			timeout:     timeout,
+		}
+	}
+}
+
+func (o noGuessOptions) validate() error {
+	if o.maxAttempts < 0 {
+		return fmt.Errorf("invalid no-guess attempts %d", o.maxAttempts)
+	}
+	if o.timeout < 0 {
+		return fmt.Errorf("invalid no-guess timeout %s", o.timeout)
+	}
+	return nil
+}
+
+func (o noGuessOptions) save(w io.Writer) error {
+	err := binary.Write(w, serialiseByteOrder, o.enabled)
+	if err != nil {
+		return err
+	}
+	return binary.Write(w, serialiseByteOrder,
+		[]int64{int64(o.maxAttempts), int64(o.timeout)})
+}
+
+func loadNoGuessOptions(r io.Reader) (o noGuessOptions, err error) {
+	err = binary.Read(r, serialiseByteOrder, &o.enabled)
+	if err != nil {
+		return
+	}
+	fields := make([]int64, 2)
+	err = binary.Read(r, serialiseByteOrder, fields)
+	if err != nil {
+		return
+	}
+	// todo int overflow
+	o.maxAttempts = int(fields[0])
+	o.timeout = time.Duration(fields[1])
+	return
+}
+
+// populateNoGuessField repeatedly populates the field until it can be solved
+// from the given start position without guessing
+func (g *FiniteGame) populateNoGuessField(startX, startY int) error {
+	maxAttempts := g.noGuess.maxAttempts
+	if maxAttempts == 0 && g.noGuess.timeout == 0 {
+		maxAttempts = DefaultNoGuessAttempts
+	}
+
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		// Stop if the budget has been used up
+		if (maxAttempts > 0 && attempt >= maxAttempts) ||
+			(g.noGuess.timeout > 0 && time.Since(start) >= g.noGuess.timeout) {
+			return ErrNoGuessBudgetExhausted
+		}
+
+		g.populateField(startX, startY)
+		if g.solvableWithoutGuessing(startX, startY) {
+			return nil
+		}
+	}
+}
+
+// solvableWithoutGuessing returns whether the populated field can be fully
+// cleared from the given start position using only logical deductions
+func (g *FiniteGame) solvableWithoutGuessing(startX, startY int) bool {
+	s := newFieldSolver(g)
+	s.reveal(Pos{startX, startY})
+	for s.step() {
+	}
+	return s.numRevealed == (g.w*g.h)-g.numMines
+}
+
+// fieldSolver plays a populated field with the same deductions as the solver
+// package, used to check whether the field needs guessing. It only reveals
+// tiles it knows are safe, so it never "hits" a mine
+type fieldSolver struct {
+	g           *FiniteGame
+	revealed    map[Pos]bool
+	mines       map[Pos]bool
+	numRevealed int
+}
+
+func newFieldSolver(g *FiniteGame) *fieldSolver {
+	return &fieldSolver{
+		g:        g,
+		revealed: make(map[Pos]bool),
+		mines:    make(map[Pos]bool),
+	}
+}
+
+func (s *fieldSolver) unknown(p Pos) bool {
+	return !s.revealed[p] && !s.mines[p]
+}
+
+// reveal uncovers the tile at the given position, flood filling empty tiles
+// the same way as FiniteGame.Uncover
+func (s *fieldSolver) reveal(p Pos) {
+	queue := []Pos{p}
+	for len(queue) > 0 {
+		pos := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if s.revealed[pos] {
+			continue
+		}
+		s.revealed[pos] = true
+		s.numRevealed++
+
+		if s.g.field[pos.Y][pos.X].Type == TileTypeEmpty {
+			for _, neighbour := range s.g.neighbouringTiles(pos.X, pos.Y) {
+				if !s.revealed[neighbour.Pos] {
+					queue = append(queue, neighbour.Pos)
+				}
+			}
+		}
+	}
+}
+
+// board returns what the solver knows about the field, as every tile is
+// visible the number of remaining mines is known too
+func (s *fieldSolver) board() *logic.Board {
+	b := &logic.Board{
+		Unknown:        make(map[logic.Pos]bool),
+		RemainingMines: s.g.numMines - len(s.mines),
+	}
+	for y := 0; y < s.g.h; y++ {
+		for x := 0; x < s.g.w; x++ {
+			if s.unknown(Pos{x, y}) {
+				b.Unknown[logic.Pos{X: x, Y: y}] = true
+			}
+		}
+	}
+
+	// Every revealed number that borders an unknown tile is a constraint
+	for pos := range s.revealed {
+		mines, _ := s.g.field[pos.Y][pos.X].Type.Number()
+		c := logic.Constraint{From: logic.Pos(pos), Mines: mines}
+		for _, neighbour := range s.g.neighbouringTiles(pos.X, pos.Y) {
+			if s.mines[neighbour.Pos] {
+				c.Mines--
+			} else if s.unknown(neighbour.Pos) {
+				c.Tiles = append(c.Tiles, logic.Pos(neighbour.Pos))
+			}
+		}
+		if len(c.Tiles) > 0 {
+			b.Constraints = append(b.Constraints, c)
+		}
+	}
+	return b
+}
+
+// step makes every deduction it can find with the simplest rule that finds
+// any, and returns whether anything was deduced
+func (s *fieldSolver) step() bool {
+	deductions := s.board().Step()
+	for _, d := range deductions {
+		if d.Mine {
+			s.mines[Pos(d.Pos)] = true
+		} else {
+			s.reveal(Pos(d.Pos))
+		}
+	}
+	return len(deductions) > 0
+}
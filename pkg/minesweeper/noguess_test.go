@@ -0,0 +1,65 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNoGuessSolvable(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewGame(16, 16, 40, WithSeed(seed), WithNoGuess(0, 0))
+		a.NoError(err)
+		g := game.(*FiniteGame)
+
+		a.NoError(g.Populate(8, 8))
+		a.Equal(GameStatePlaying, g.State())
+		a.True(g.solvableWithoutGuessing(8, 8))
+	}
+}
+
+func TestNoGuessBudgetExhausted(t *testing.T) {
+	a := assert.New(t)
+
+	// A 2 wide board always ends in a 50/50 on the row with the mine
+	game, err := NewGame(2, 6, 1, WithNoGuess(10, 0))
+	a.NoError(err)
+	g := game.(*FiniteGame)
+
+	a.ErrorIs(g.Populate(0, 0), ErrNoGuessBudgetExhausted)
+	a.Equal(GameStateStart, g.State())
+
+	// Uncover should fall back to a field that needs guessing, but keep the
+	// error
+	a.Equal(GameStatePlaying, g.Uncover(0, 0))
+	a.ErrorIs(g.NoGuessErr(), ErrNoGuessBudgetExhausted)
+	a.NoError(g.Reset(1))
+	a.NoError(g.NoGuessErr())
+
+	// Without a budget, a default number of fields are tried
+	game, err = NewGame(2, 6, 1, WithNoGuess(0, 0))
+	a.NoError(err)
+	a.ErrorIs(game.(*FiniteGame).Populate(0, 0), ErrNoGuessBudgetExhausted)
+
+	_, err = NewGame(2, 6, 1, WithNoGuess(-1, 0))
+	a.Error(err)
+	_, err = NewGame(2, 6, 1, WithNoGuess(0, -time.Second))
+	a.Error(err)
+}
+
+func TestNoGuessSerialiseRoundtrip(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame(16, 16, 40, WithNoGuess(100, 0))
+	a.NoError(err)
+
+	var buf bytes.Buffer
+	a.NoError(game.Save(&buf))
+
+	loadedGame, err := Load(&buf)
+	a.NoError(err)
+	a.Equal(game.(*FiniteGame).noGuess, loadedGame.(*FiniteGame).noGuess)
+}
@@ -0,0 +1,92 @@
+package minesweeper
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Option configures a game when it's created, see NewGame and NewInfiniteGame
+type Option func(*options)
+
+// options stores the configuration shared by all the game types
+type options struct {
+	// The seed for the game's random number generator
+	seed int64
+
+	// How no-guess fields are generated, see WithNoGuess
+	noGuess noGuessOptions
+
+	// Which tiles are kept free of mines for the first move, see
+	// WithFirstMovePolicy
+	firstMove firstMoveOptions
+
+	// Which moves can be undone, see WithUndoPolicy
+	undoPolicy UndoPolicy
+
+	// Whether a finite game's edges wrap around, see WithToroidal
+	toroidal bool
+
+	// Which tiles are next to each other, see WithNeighbourhood
+	neighbourhood Neighbourhood
+
+	// The most mines a tile can have, see WithMultiMines
+	maxMines int
+
+	// The number of lives the game starts with, or 0 for the game's default,
+	// see WithLives
+	lives int
+
+	// When an infinite game ends, or 0 for no limit, see WithTimeLimit and
+	// WithTargetScore
+	timeLimit   time.Duration
+	targetScore int
+
+	// The number of mines in each chunk of an infinite game, see WithDensity
+	density Density
+
+	// How many of an infinite game's chunks are kept in memory, and where the
+	// rest are kept, see WithChunkCache
+	maxChunks  int
+	chunkStore ChunkStore
+}
+
+func newOptions(opts []Option) options {
+	// By default the seed comes from the global source, so games are as
+	// random as the global source is
+	o := options{
+		seed:     rand.Int63(),
+		maxMines: 1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithSeed sets the seed used to place the game's mines. Two games with the
+// same settings and seed will generate the same mines when played the same way
+func WithSeed(seed int64) Option {
+	return func(o *options) {
+		o.seed = seed
+	}
+}
+
+// WithToroidal makes the edges of a finite game's field wrap around, so the
+// tiles on the left edge are next to the tiles on the right edge, and the top
+// edge is next to the bottom edge. This means the tiles on the edges have as
+// many neighbours as the rest. Hex games must have an even height to wrap
+func WithToroidal() Option {
+	return func(o *options) {
+		o.toroidal = true
+	}
+}
+
+// WithMultiMines lets each tile of a finite game have up to maxMines mines.
+// The numbers show the total number of mines in the neighbouring tiles, and a
+// tile can be flagged up to maxMines times, after which flagging it again
+// removes its flags. It can't be used with WithNoGuess, or with infinite games
+func WithMultiMines(maxMines int) Option {
+	return func(o *options) {
+		o.maxMines = maxMines
+	}
+}
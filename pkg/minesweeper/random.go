@@ -0,0 +1,144 @@
+package minesweeper
+
+import "math/rand"
+
+// splitMix64 scrambles the bits of x, see
+// https://prng.di.unimi.it/splitmix64.c
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// hashPos returns a random number for the given position, which is always the
+// same for the same seed
+func hashPos(seed int64, p Pos) uint64 {
+	h := splitMix64(uint64(seed))
+	h = splitMix64(h ^ uint64(p.X))
+	return splitMix64(h ^ uint64(p.Y))
+}
+
+// splitMixSource is a rand.Source64 which is much cheaper to create than
+// rand.NewSource, so one can be created for every chunk of an infinite game
+type splitMixSource struct {
+	state uint64
+}
+
+func (s *splitMixSource) Uint64() uint64 {
+	x := splitMix64(s.state)
+	s.state += 0x9e3779b97f4a7c15
+	return x
+}
+
+func (s *splitMixSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+func (s *splitMixSource) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+// countingSource is a seeded rand.Source64 that counts the number of values
+// drawn from it. This means the source's state can be saved as just the seed
+// and the number of draws
+type countingSource struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+// newCountingSource creates a source from the given seed, and skips forward
+// the given number of draws
+func newCountingSource(seed int64, draws uint64) *countingSource {
+	s := &countingSource{
+		src:  rand.NewSource(seed).(rand.Source64),
+		seed: seed,
+	}
+	for s.draws < draws {
+		s.Uint64()
+	}
+	return s
+}
+
+// clone returns a copy of the source in the same state
+func (s *countingSource) clone() *countingSource {
+	return newCountingSource(s.seed, s.draws)
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
+
+// smallSampleSlots is the most slots sampleTiles stores all of, see
+// sampleTiles
+const smallSampleSlots = 1 << 16
+
+// sampleTiles picks where to place numMines mines on numTiles tiles, where
+// each tile can have up to maxMines mines, apart from the tiles at the given
+// (sorted and distinct) indexes which can't have any. It returns the index of
+// the tile for each mine.
+//
+// Every tile has maxMines slots for mines, and the slots are picked by
+// shuffling them (Fisher-Yates). For large fields, only the slots that have
+// been moved by the shuffle are stored, so it takes O(numMines) time and
+// memory however many tiles there are
+func sampleTiles(rng *rand.Rand, numTiles, maxMines, numMines int,
+	excluded []int) []int {
+	slots := (numTiles - len(excluded)) * maxMines
+
+	// Small fields store every slot, which is quicker than a map
+	var all []int
+	var moved map[int]int
+	if slots <= smallSampleSlots {
+		all = make([]int, slots)
+		for i := range all {
+			all[i] = i
+		}
+	} else {
+		moved = make(map[int]int, numMines)
+	}
+	slot := func(i int) int {
+		if all != nil {
+			return all[i]
+		}
+		if s, ok := moved[i]; ok {
+			return s
+		}
+		return i
+	}
+
+	tiles := make([]int, numMines)
+	for i := range tiles {
+		// Swap a random slot from the rest into the next place
+		j := i + rng.Intn(slots-i)
+		picked := slot(j)
+		if all != nil {
+			all[j] = all[i]
+		} else {
+			moved[j] = slot(i)
+		}
+
+		// Skip over the excluded tiles
+		tile := picked / maxMines
+		for _, e := range excluded {
+			if tile >= e {
+				tile++
+			}
+		}
+		tiles[i] = tile
+	}
+	return tiles
+}
@@ -0,0 +1,82 @@
+package minesweeper
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+// chiSquared returns the chi-squared statistic for the given counts, which
+// should all be the same
+func chiSquared(counts []int, expected float64) float64 {
+	stat := 0.0
+	for _, count := range counts {
+		diff := float64(count) - expected
+		stat += diff * diff / expected
+	}
+	return stat
+}
+
+func TestSampleTiles(t *testing.T) {
+	a := assert.New(t)
+	rng := rand.New(rand.NewSource(1))
+
+	// Every tile is picked when there are as many mines as tiles
+	tiles := sampleTiles(rng, 20, 1, 18, []int{3, 11})
+	a.Len(tiles, 18)
+	seen := make(map[int]bool)
+	for _, tile := range tiles {
+		a.False(seen[tile])
+		seen[tile] = true
+		a.NotContains([]int{3, 11}, tile)
+		a.True(tile >= 0 && tile < 20)
+	}
+
+	// Tiles can have several mines, but no more than the maximum
+	perTile := make(map[int]int)
+	for _, tile := range sampleTiles(rng, 10, 3, 30, nil) {
+		perTile[tile]++
+	}
+	for tile := 0; tile < 10; tile++ {
+		a.Equal(3, perTile[tile])
+	}
+
+	// Large numbers of tiles don't need much memory
+	a.Len(sampleTiles(rng, 1<<30, 1, 10, []int{0}), 10)
+}
+
+func TestSampleTilesUniform(t *testing.T) {
+	a := assert.New(t)
+	rng := rand.New(rand.NewSource(1))
+
+	// Every tile that isn't excluded should be picked as often as the others
+	const numTiles, numMines, samples = 25, 5, 20000
+	excluded := []int{0, 1, 5, 6}
+	counts := make([]int, numTiles)
+	for i := 0; i < samples; i++ {
+		for _, tile := range sampleTiles(rng, numTiles, 1, numMines, excluded) {
+			counts[tile]++
+		}
+	}
+	var candidates []int
+	for tile, count := range counts {
+		if containsInt(excluded, tile) {
+			a.Zero(count)
+		} else {
+			candidates = append(candidates, count)
+		}
+	}
+
+	// 45.3 is the critical value for 20 degrees of freedom at p = 0.001
+	expected := float64(samples*numMines) / float64(len(candidates))
+	a.Less(chiSquared(candidates, expected), 45.3)
+}
+
+func containsInt(s []int, n int) bool {
+	for _, i := range s {
+		if i == n {
+			return true
+		}
+	}
+	return false
+}
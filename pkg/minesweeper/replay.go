@@ -0,0 +1,364 @@
+package minesweeper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ActionType is the type of move in a replay
+type ActionType uint8
+
+const (
+	ActionUncover = ActionType(iota)
+	ActionFlag
+	ActionChord
+	ActionUndo
+	ActionRedo
+
+	// ActionPopulate is a call to FiniteGame.Populate
+	ActionPopulate
+)
+
+func (t ActionType) String() string {
+	switch t {
+	case ActionUncover:
+		return "uncover"
+	case ActionFlag:
+		return "flag"
+	case ActionChord:
+		return "chord"
+	case ActionUndo:
+		return "undo"
+	case ActionRedo:
+		return "redo"
+	case ActionPopulate:
+		return "populate"
+	default:
+		return "unknown"
+	}
+}
+
+// Action is a single move made in a game
+type Action struct {
+	// The time of the action, since the replay started recording
+	Time time.Duration
+
+	Type ActionType
+
+	// The coordinate of the action, unused for undo and redo
+	X, Y int
+
+	// Whether the action placed the mines of a finite game, and the number
+	// of random values drawn before they were placed
+	populated bool
+	draws     uint64
+}
+
+// Replay is a recording of every move made in a game, that can be played back.
+// The moves are recorded from when the game was created (or reset)
+type Replay struct {
+	// When the recording started
+	start time.Time
+
+	// The game before any moves were made, as save data
+	initial []byte
+
+	actions []Action
+
+	// The game being played back, and the index of the next action to play
+	game Game
+	next int
+}
+
+func newReplay(initial []byte) *Replay {
+	return &Replay{
+		start:   time.Now(),
+		initial: initial,
+	}
+}
+
+// record adds an action to the replay
+func (r *Replay) record(t ActionType, x, y int) {
+	if r == nil {
+		return
+	}
+	r.actions = append(r.actions, Action{
+		Time: time.Since(r.start),
+		Type: t,
+		X:    x,
+		Y:    y,
+	})
+}
+
+// populated records that the last action placed the mines of a finite game,
+// from the given number of random draws
+func (r *Replay) populated(draws uint64) {
+	if r == nil || len(r.actions) == 0 {
+		return
+	}
+	action := &r.actions[len(r.actions)-1]
+	action.populated = true
+	action.draws = draws
+}
+
+// copy returns a copy of the replay's recording, to be played back
+func (r *Replay) copy() *Replay {
+	if r == nil {
+		return nil
+	}
+	return &Replay{
+		start:   r.start,
+		initial: r.initial,
+		actions: append([]Action(nil), r.actions...),
+	}
+}
+
+// StartTime returns when the replay started recording
+func (r *Replay) StartTime() time.Time {
+	return r.start
+}
+
+// Actions returns the actions in the replay
+func (r *Replay) Actions() []Action {
+	return r.actions
+}
+
+// Duration returns the time of the last action in the replay
+func (r *Replay) Duration() time.Duration {
+	if len(r.actions) == 0 {
+		return 0
+	}
+	return r.actions[len(r.actions)-1].Time
+}
+
+// Game returns the game being played back, starting from the game before
+// any moves were made. The game must not be modified
+func (r *Replay) Game() (Game, error) {
+	if r.game == nil {
+		err := r.rewind()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r.game, nil
+}
+
+// Position returns the time of the last action that was played back
+func (r *Replay) Position() time.Duration {
+	if r.next == 0 {
+		return 0
+	}
+	return r.actions[r.next-1].Time
+}
+
+// rewind restarts the playback from the game before any moves were made
+func (r *Replay) rewind() (err error) {
+	r.game, err = Load(bytes.NewReader(r.initial))
+	r.next = 0
+	return
+}
+
+// Step plays back the next action. Returns false if there are no actions
+// left to play back
+func (r *Replay) Step() (bool, error) {
+	game, err := r.Game()
+	if err != nil {
+		return false, err
+	}
+	if r.next >= len(r.actions) {
+		return false, nil
+	}
+
+	action := r.actions[r.next]
+	// Place the same mines as the recorded game, instead of generating the
+	// field again
+	if action.populated {
+		populater, ok := game.(interface {
+			populateFrom(x, y int, draws uint64)
+		})
+		if !ok {
+			return false, fmt.Errorf("%T can't be populated", game)
+		}
+		populater.populateFrom(action.X, action.Y, action.draws)
+	}
+	switch action.Type {
+	case ActionUncover:
+		game.Uncover(action.X, action.Y)
+	case ActionFlag:
+		game.Flag(action.X, action.Y)
+	case ActionChord:
+		game.Chord(action.X, action.Y)
+	case ActionUndo:
+		game.Undo()
+	case ActionRedo:
+		game.Redo()
+	case ActionPopulate:
+		// The mines have already been placed if the call succeeded, and
+		// nothing happened if it failed
+	default:
+		return false, fmt.Errorf("unknown action type %d", action.Type)
+	}
+	r.next++
+	return true, nil
+}
+
+// Seek plays back every action up to and including the given time,
+// restarting the playback if the time is before the current position
+func (r *Replay) Seek(t time.Duration) error {
+	if r.game == nil || t < r.Position() {
+		err := r.rewind()
+		if err != nil {
+			return err
+		}
+	}
+	for r.next < len(r.actions) && r.actions[r.next].Time <= t {
+		_, err := r.Step()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Appearance returns the appearance of the game being played back, see
+// Game.Appearance
+func (r *Replay) Appearance(x, y, w, h int) (map[Pos]TileType, error) {
+	game, err := r.Game()
+	if err != nil {
+		return nil, err
+	}
+	return game.Appearance(x, y, w, h), nil
+}
+
+// Save the replay into the given io.Writer
+func (r *Replay) Save(w io.Writer) error {
+	// Write the start time and the initial game
+	err := binary.Write(w, serialiseByteOrder,
+		[]int64{r.start.UnixNano(), int64(len(r.initial))})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(r.initial)
+	if err != nil {
+		return err
+	}
+
+	// Write the number of actions
+	err = binary.Write(w, serialiseByteOrder, int64(len(r.actions)))
+	if err != nil {
+		return err
+	}
+
+	// Write the actions
+	for _, action := range r.actions {
+		err = binary.Write(w, serialiseByteOrder, int64(action.Time))
+		if err != nil {
+			return err
+		}
+		err = binary.Write(w, serialiseByteOrder, action.Type)
+		if err != nil {
+			return err
+		}
+		err = binary.Write(w, serialiseByteOrder,
+			[]int64{int64(action.X), int64(action.Y)})
+		if err != nil {
+			return err
+		}
+		err = binary.Write(w, serialiseByteOrder, action.populated)
+		if err != nil {
+			return err
+		}
+		err = binary.Write(w, serialiseByteOrder, action.draws)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadReplay loads a replay from the given io.Reader
+func LoadReplay(r io.Reader) (*Replay, error) {
+	replay := new(Replay)
+
+	// Read the start time and the initial game
+	fields := make([]int64, 2)
+	err := binary.Read(r, serialiseByteOrder, fields)
+	if err != nil {
+		return nil, err
+	}
+	replay.start = time.Unix(0, fields[0])
+	replay.initial = make([]byte, fields[1])
+	_, err = io.ReadFull(r, replay.initial)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the number of actions
+	var numActions int64
+	err = binary.Read(r, serialiseByteOrder, &numActions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the actions
+	replay.actions = make([]Action, numActions)
+	for i := range replay.actions {
+		var t int64
+		err = binary.Read(r, serialiseByteOrder, &t)
+		if err != nil {
+			return nil, err
+		}
+		var actionType ActionType
+		err = binary.Read(r, serialiseByteOrder, &actionType)
+		if err != nil {
+			return nil, err
+		}
+		pos := make([]int64, 2)
+		err = binary.Read(r, serialiseByteOrder, pos)
+		if err != nil {
+			return nil, err
+		}
+		var populated bool
+		err = binary.Read(r, serialiseByteOrder, &populated)
+		if err != nil {
+			return nil, err
+		}
+		var draws uint64
+		err = binary.Read(r, serialiseByteOrder, &draws)
+		if err != nil {
+			return nil, err
+		}
+		// todo int overflow
+		replay.actions[i] = Action{
+			Time:      time.Duration(t),
+			Type:      actionType,
+			X:         int(pos[0]),
+			Y:         int(pos[1]),
+			populated: populated,
+			draws:     draws,
+		}
+	}
+	return replay, nil
+}
+
+// saveGameReplay writes a game's replay, which is nil when saving the game
+// before any moves were made for the replay itself
+func saveGameReplay(w io.Writer, r *Replay) error {
+	err := binary.Write(w, serialiseByteOrder, r != nil)
+	if err != nil || r == nil {
+		return err
+	}
+	return r.Save(w)
+}
+
+func loadGameReplay(r io.Reader) (*Replay, error) {
+	var present bool
+	err := binary.Read(r, serialiseByteOrder, &present)
+	if err != nil || !present {
+		return nil, err
+	}
+	return LoadReplay(r)
+}
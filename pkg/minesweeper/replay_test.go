@@ -0,0 +1,168 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// playAndRecord makes some moves in the game, and returns the game's
+// appearance in the given rect after each move
+func playAndRecord(game Game, x, y, w, h int) (appearances []map[Pos]TileType) {
+	moves := []func(){
+		func() { game.Flag(0, 0) },
+		func() { game.Uncover(8, 8) },
+		func() { game.Flag(0, 0) },
+		func() { game.Uncover(0, 0) },
+		func() { game.Undo() },
+		func() { game.Redo() },
+		func() { game.Chord(8, 8) },
+		func() { game.Uncover(15, 15) },
+	}
+	for _, move := range moves {
+		move()
+		appearances = append(appearances, game.Appearance(x, y, w, h))
+	}
+	return
+}
+
+func testReplay(a *assert.Assertions, replay *Replay,
+	appearances []map[Pos]TileType, x, y, w, h int) {
+	a.Len(replay.Actions(), len(appearances))
+
+	// Step through the replay
+	for _, expected := range appearances {
+		ok, err := replay.Step()
+		a.NoError(err)
+		a.True(ok)
+		actual, err := replay.Appearance(x, y, w, h)
+		a.NoError(err)
+		a.Equal(expected, actual)
+	}
+	ok, err := replay.Step()
+	a.NoError(err)
+	a.False(ok)
+
+	// Seek backwards and forwards through the replay
+	for _, i := range []int{2, 0, 5, 3} {
+		seekTime := replay.Actions()[i].Time
+		a.NoError(replay.Seek(seekTime))
+		// Later actions might have been made at the same time
+		for i+1 < len(appearances) && replay.Actions()[i+1].Time <= seekTime {
+			i++
+		}
+		actual, err := replay.Appearance(x, y, w, h)
+		a.NoError(err)
+		a.Equal(appearances[i], actual)
+	}
+}
+
+func TestFiniteReplay(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewGame(16, 16, 40, WithSeed(seed))
+		a.NoError(err)
+		appearances := playAndRecord(game, 0, 0, 16, 16)
+		testReplay(a, game.Replay(), appearances, 0, 0, 16, 16)
+
+		// The replay should be saved with the game
+		var buf bytes.Buffer
+		a.NoError(game.Save(&buf))
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+		testReplay(a, loadedGame.Replay(), appearances, 0, 0, 16, 16)
+	}
+}
+
+func TestFinitePopulateReplay(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		// The mines are placed for a different tile than the first move
+		game, err := NewGame(16, 16, 40, WithSeed(seed))
+		a.NoError(err)
+		a.NoError(game.(*FiniteGame).Populate(3, 3))
+		game.Uncover(12, 12)
+		game.Uncover(3, 3)
+
+		replay := game.Replay()
+		a.Equal(ActionPopulate, replay.Actions()[0].Type)
+		a.Len(replay.Actions(), 3)
+		for i := 0; i < 3; i++ {
+			ok, err := replay.Step()
+			a.NoError(err)
+			a.True(ok)
+		}
+		actual, err := replay.Appearance(0, 0, 16, 16)
+		a.NoError(err)
+		a.Equal(game.Appearance(0, 0, 16, 16), actual)
+	}
+}
+
+func TestFiniteNoGuessReplay(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		// The field depends on how many fields are tried before the timeout,
+		// which the replay can't repeat
+		game, err := NewGame(30, 16, 99, WithSeed(seed),
+			WithNoGuess(0, time.Millisecond))
+		a.NoError(err)
+		if seed%2 == 0 {
+			game.(*FiniteGame).Populate(15, 8)
+		}
+		game.Uncover(15, 8)
+
+		var buf bytes.Buffer
+		a.NoError(game.Save(&buf))
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+		for _, replay := range []*Replay{game.Replay(), loadedGame.Replay()} {
+			a.NoError(replay.Seek(replay.Duration()))
+			actual, err := replay.Appearance(0, 0, 30, 16)
+			a.NoError(err)
+			a.Equal(game.Appearance(0, 0, 30, 16), actual)
+
+			// The mines are the same too
+			played, err := replay.Game()
+			a.NoError(err)
+			a.Equal(game.(*FiniteGame).field, played.(*FiniteGame).field)
+		}
+	}
+}
+
+func TestInfiniteReplay(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		game, err := NewInfiniteGame(40, WithSeed(seed))
+		a.NoError(err)
+		appearances := playAndRecord(game, -16, -16, 48, 48)
+		testReplay(a, game.Replay(), appearances, -16, -16, 48, 48)
+
+		var buf bytes.Buffer
+		a.NoError(game.Save(&buf))
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+		testReplay(a, loadedGame.Replay(), appearances, -16, -16, 48, 48)
+	}
+}
+
+func TestReplaySerialiseRoundtrip(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame(16, 16, 40, WithSeed(1))
+	a.NoError(err)
+	appearances := playAndRecord(game, 0, 0, 16, 16)
+
+	var buf bytes.Buffer
+	a.NoError(game.Replay().Save(&buf))
+	replay, err := LoadReplay(&buf)
+	a.NoError(err)
+
+	a.Equal(game.Replay().StartTime().UnixNano(), replay.StartTime().UnixNano())
+	a.Equal(game.Replay().Actions(), replay.Actions())
+	testReplay(a, replay, appearances, 0, 0, 16, 16)
+}
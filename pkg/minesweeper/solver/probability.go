@@ -0,0 +1,276 @@
+package solver
+
+import (
+	ms "github.com/bhollier/minesweeper/pkg/minesweeper"
+	"github.com/bhollier/minesweeper/pkg/minesweeper/internal/logic"
+	"math"
+)
+
+// defaultPrior is the chance of a tile being a mine when the game doesn't
+// give the number of mines or their density
+const defaultPrior = 0.5
+
+// Probabilities returns the probability that each hidden (or flagged) tile in
+// the given area of the game is a mine, given the numbers the player can see.
+// For a finite game, the whole game is used along with the number of
+// remaining mines. For an infinite game, each tile is assumed to be a mine
+// with the mine density of the chunk it's in (see ms.WithDensity). The tiles
+// the single point and subset rules can deduce are certain, and groups of
+// hidden tiles that are still too big to enumerate are approximated
+func Probabilities(g ms.Game, x, y, w, h int) map[ms.Pos]float64 {
+	// Solve the whole game if possible, so the remaining mines can be used
+	s := New(g, x, y, w, h)
+	if sized, ok := g.(interface{ Size() (w, h int) }); ok {
+		gameW, gameH := sized.Size()
+		s = New(g, 0, 0, gameW, gameH)
+	}
+	b := s.board()
+
+	probabilities := make(map[ms.Pos]float64)
+	// The tiles that can be deduced without enumerating are certain, and
+	// taking them out usually splits up the groups of hidden tiles
+	for _, d := range b.Simplify() {
+		if d.Mine {
+			probabilities[ms.Pos(d.Pos)] = 1
+		} else {
+			probabilities[ms.Pos(d.Pos)] = 0
+		}
+	}
+
+	components, interior := b.Components()
+	enumerated := make([]*logic.Component, 0, len(components))
+	var approximated []*logic.Component
+	for _, comp := range components {
+		if comp.Solutions == nil {
+			approximated = append(approximated, comp)
+		} else {
+			enumerated = append(enumerated, comp)
+		}
+	}
+
+	// The groups that are too big to enumerate keep their numbers, but their
+	// probabilities are only estimated
+	p := prior(g)
+	approximatedMines := 0.0
+	for _, comp := range approximated {
+		for t, mine := range approximate(comp, p) {
+			probabilities[ms.Pos(comp.Tiles[t])] = mine
+			approximatedMines += mine
+		}
+	}
+
+	if b.RemainingMines >= 0 {
+		// The approximated groups are assumed to have their expected number
+		// of mines
+		remaining := b.RemainingMines - int(math.Round(approximatedMines))
+		if remaining < 0 {
+			remaining = 0
+		}
+		globalProbabilities(enumerated, interior, remaining, probabilities)
+	} else {
+		priorProbabilities(enumerated, interior, p, probabilities)
+	}
+
+	// Only return the tiles in the requested area
+	for pos := range probabilities {
+		if pos.X < x || pos.X >= x+w || pos.Y < y || pos.Y >= y+h {
+			delete(probabilities, pos)
+		}
+	}
+	return probabilities
+}
+
+// approximationRounds is the number of times approximate adjusts the
+// probabilities to fit each number
+const approximationRounds = 50
+
+// approximate estimates the probability of each tile in a component being a
+// mine, for components too big to enumerate. Starting from the prior, the
+// probabilities of the tiles next to each number are scaled (up to 1) so they
+// add up to the number of mines it needs, which is repeated so the numbers
+// settle on probabilities that fit all of them
+func approximate(comp *logic.Component, prior func(logic.Pos) float64) []float64 {
+	index := make(map[logic.Pos]int, len(comp.Tiles))
+	probabilities := make([]float64, len(comp.Tiles))
+	for i, pos := range comp.Tiles {
+		index[pos] = i
+		// A tile with no chance of being a mine can't be scaled up
+		probabilities[i] = math.Max(prior(pos), 1e-6)
+	}
+
+	for round := 0; round < approximationRounds; round++ {
+		for _, c := range comp.Constraints {
+			sum := 0.0
+			for _, pos := range c.Tiles {
+				sum += probabilities[index[pos]]
+			}
+			scale := float64(c.Mines) / sum
+			for _, pos := range c.Tiles {
+				i := index[pos]
+				probabilities[i] = math.Min(probabilities[i]*scale, 1)
+			}
+		}
+	}
+	return probabilities
+}
+
+// prior returns a function that gives the chance of a tile being a mine
+// before any numbers are taken into account. For an infinite game it's the
+// density of the chunk the tile is in
+func prior(g ms.Game) func(logic.Pos) float64 {
+	infinite, ok := g.(interface{ ChunkMines(chunk ms.Pos) int })
+	if !ok {
+		return func(logic.Pos) float64 {
+			return defaultPrior
+		}
+	}
+	densities := make(map[ms.Pos]float64)
+	return func(pos logic.Pos) float64 {
+		chunk := ms.Pos{X: floorDiv(pos.X, ms.ChunkSize),
+			Y: floorDiv(pos.Y, ms.ChunkSize)}
+		density, ok := densities[chunk]
+		if !ok {
+			density = float64(infinite.ChunkMines(chunk)) /
+				(ms.ChunkSize * ms.ChunkSize)
+			densities[chunk] = density
+		}
+		return density
+	}
+}
+
+// floorDiv divides a by b, rounding down instead of towards 0
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+// globalProbabilities calculates the probabilities when the number of mines
+// in the components and interior must add up to remaining
+func globalProbabilities(components []*logic.Component, interior []logic.Pos,
+	remaining int, probabilities map[ms.Pos]float64) {
+	// The relative number of ways r mines can be placed in the interior
+	interiorWeights := binomialWeights(len(interior))
+
+	// restWeight returns the relative number of ways the rest of the tiles
+	// can have the remaining mines, given dist (the number of ways the other
+	// components can have each number of mines) and the k mines already used
+	restWeight := func(dist []float64, k int) (weight float64) {
+		for s, w := range dist {
+			r := remaining - k - s
+			if r >= 0 && r < len(interiorWeights) {
+				weight += w * interiorWeights[r]
+			}
+		}
+		return
+	}
+
+	for i, comp := range components {
+		others := convolve(components, i)
+		total := 0.0
+		mines := make([]float64, len(comp.Tiles))
+		for k, sol := range comp.Solutions {
+			w := restWeight(others, k)
+			total += sol.Count * w
+			for t, m := range sol.Mines {
+				mines[t] += m * w
+			}
+		}
+		if total > 0 {
+			for t, pos := range comp.Tiles {
+				probabilities[ms.Pos(pos)] = mines[t] / total
+			}
+		}
+	}
+
+	if len(interior) > 0 {
+		total, mines := 0.0, 0.0
+		for s, w := range convolve(components, -1) {
+			r := remaining - s
+			if r < 0 || r >= len(interiorWeights) {
+				continue
+			}
+			w *= interiorWeights[r]
+			total += w
+			mines += w * float64(r) / float64(len(interior))
+		}
+		if total > 0 {
+			for _, pos := range interior {
+				probabilities[ms.Pos(pos)] = mines / total
+			}
+		}
+	}
+}
+
+// priorProbabilities calculates the probabilities when every tile is
+// independently a mine with the probability given by prior
+func priorProbabilities(components []*logic.Component, interior []logic.Pos,
+	prior func(logic.Pos) float64, probabilities map[ms.Pos]float64) {
+	for _, comp := range components {
+		total, mines := comp.Weighted(prior)
+		if total > 0 {
+			for t, pos := range comp.Tiles {
+				probabilities[ms.Pos(pos)] = mines[t] / total
+			}
+		}
+	}
+
+	for _, pos := range interior {
+		probabilities[ms.Pos(pos)] = prior(pos)
+	}
+}
+
+// convolve returns the relative number of arrangements of the components
+// (excluding the component at the given index) for each total number of
+// mines
+func convolve(components []*logic.Component, exclude int) []float64 {
+	dist := []float64{1}
+	for i, comp := range components {
+		if i == exclude {
+			continue
+		}
+		next := make([]float64, len(dist)+len(comp.Tiles))
+		for s, w := range dist {
+			for k, sol := range comp.Solutions {
+				next[s+k] += w * sol.Count
+			}
+		}
+		dist = normalise(next)
+	}
+	return dist
+}
+
+// binomialWeights returns n choose r for every r from 0 to n, scaled so they
+// don't overflow
+func binomialWeights(n int) []float64 {
+	lgamma := func(x int) float64 {
+		v, _ := math.Lgamma(float64(x))
+		return v
+	}
+	logs := make([]float64, n+1)
+	maxLog := math.Inf(-1)
+	for r := range logs {
+		logs[r] = lgamma(n+1) - lgamma(r+1) - lgamma(n-r+1)
+		maxLog = math.Max(maxLog, logs[r])
+	}
+	weights := make([]float64, n+1)
+	for r, l := range logs {
+		weights[r] = math.Exp(l - maxLog)
+	}
+	return weights
+}
+
+// normalise scales the weights so the biggest is 1
+func normalise(weights []float64) []float64 {
+	biggest := 0.0
+	for _, w := range weights {
+		biggest = math.Max(biggest, w)
+	}
+	if biggest > 0 {
+		for i := range weights {
+			weights[i] /= biggest
+		}
+	}
+	return weights
+}
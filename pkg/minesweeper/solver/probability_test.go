@@ -0,0 +1,218 @@
+package solver
+
+import (
+	ms "github.com/bhollier/minesweeper/pkg/minesweeper"
+	"github.com/bhollier/minesweeper/pkg/minesweeper/internal/logic"
+	"github.com/stretchr/testify/assert"
+	"math/bits"
+	"testing"
+)
+
+func TestProbabilitiesPattern(t *testing.T) {
+	a := assert.New(t)
+
+	g := newFakeGame(2,
+		[]ms.TileType{H, H, H},
+		[]ms.TileType{ms.TileType1, ms.TileType2, ms.TileType1})
+	a.Equal(map[ms.Pos]float64{
+		{X: 0, Y: 0}: 1,
+		{X: 1, Y: 0}: 0,
+		{X: 2, Y: 0}: 1,
+	}, Probabilities(g, 0, 0, g.w, g.h))
+
+	g = newFakeGame(1,
+		[]ms.TileType{H, H},
+		[]ms.TileType{ms.TileType1, ms.TileType1})
+	a.Equal(map[ms.Pos]float64{
+		{X: 0, Y: 0}: 0.5,
+		{X: 1, Y: 0}: 0.5,
+	}, Probabilities(g, 0, 0, g.w, g.h))
+}
+
+// bruteForceProbabilities calculates the probabilities by trying every
+// arrangement of the remaining mines in the hidden tiles
+func bruteForceProbabilities(g ms.Game, remaining int) map[ms.Pos]float64 {
+	w, h := g.(interface{ Size() (w, h int) }).Size()
+	appearance := g.Appearance(0, 0, w, h)
+	var hidden []ms.Pos
+	index := make(map[ms.Pos]int)
+	for pos, tileType := range appearance {
+		if isHidden(tileType) {
+			index[pos] = len(hidden)
+			hidden = append(hidden, pos)
+		}
+	}
+
+	// Convert each number to a mask of its hidden neighbours
+	type number struct {
+		mask  int
+		mines int
+	}
+	var numbers []number
+	for pos, tileType := range appearance {
+		if mines, ok := tileType.Number(); ok {
+			n := number{mines: mines}
+			for _, neighbour := range neighbours(g, pos) {
+				if i, ok := index[neighbour]; ok {
+					n.mask |= 1 << i
+				}
+			}
+			numbers = append(numbers, n)
+		}
+	}
+
+	total := 0
+	counts := make([]int, len(hidden))
+	for mask := 0; mask < 1<<len(hidden); mask++ {
+		if bits.OnesCount(uint(mask)) != remaining {
+			continue
+		}
+		consistent := true
+		for _, n := range numbers {
+			if bits.OnesCount(uint(mask&n.mask)) != n.mines {
+				consistent = false
+				break
+			}
+		}
+		if !consistent {
+			continue
+		}
+
+		total++
+		for i := range hidden {
+			if mask&(1<<i) != 0 {
+				counts[i]++
+			}
+		}
+	}
+
+	probabilities := make(map[ms.Pos]float64)
+	for i, pos := range hidden {
+		probabilities[pos] = float64(counts[i]) / float64(total)
+	}
+	return probabilities
+}
+
+func TestProbabilitiesBruteForce(t *testing.T) {
+	a := assert.New(t)
+
+	newCrossGame := func(w, h, numMines int, opts ...ms.Option) (ms.Game, error) {
+		opts = append(opts, ms.WithNeighbourhood(ms.NeighbourhoodCross))
+		return ms.NewGame(w, h, numMines, opts...)
+	}
+	for _, newGame := range []func(int, int, int, ...ms.Option) (ms.Game, error){
+		ms.NewGame, ms.NewHexGame, newCrossGame} {
+		for seed := int64(0); seed < 20; seed++ {
+			g, err := newGame(5, 5, 6, ms.WithSeed(seed))
+			a.NoError(err)
+			g.Uncover(0, 0)
+			if g.State() != ms.GameStatePlaying {
+				continue
+			}
+
+			expected := bruteForceProbabilities(g, 6)
+			actual := Probabilities(g, 0, 0, 5, 5)
+			a.Len(actual, len(expected))
+			for pos, p := range expected {
+				a.InDelta(p, actual[pos], 1e-9, "pos %v", pos)
+			}
+
+			// A smaller area should give the same probabilities
+			for pos, p := range Probabilities(g, 1, 1, 2, 2) {
+				a.InDelta(expected[pos], p, 1e-9, "pos %v", pos)
+			}
+		}
+	}
+}
+
+func TestProbabilitiesLargeFrontier(t *testing.T) {
+	a := assert.New(t)
+
+	large := 0
+	for seed := int64(0); seed < 10; seed++ {
+		g, err := ms.NewGame(30, 16, 60, ms.WithSeed(seed))
+		a.NoError(err)
+		g.Uncover(15, 8)
+
+		// Play until the solver is stuck, checking the tiles it can deduce are
+		// certain along the way
+		s := New(g, 0, 0, 30, 16)
+		for g.State() == ms.GameStatePlaying {
+			components, _ := s.board().Components()
+			for _, comp := range components {
+				if len(comp.Tiles) > logic.MaxEnumerationTiles {
+					large++
+				}
+			}
+
+			deductions := s.Step()
+			if deductions == nil {
+				break
+			}
+			probabilities := Probabilities(g, 0, 0, 30, 16)
+			for pos, p := range probabilities {
+				a.True(p >= 0 && p <= 1, "seed %d, %v", seed, pos)
+			}
+			for _, d := range deductions {
+				expected := 0.0
+				if d.Mine {
+					expected = 1
+				}
+				a.InDelta(expected, probabilities[d.Pos], 1e-9, "seed %d, %v",
+					seed, d.Pos)
+			}
+			for _, d := range deductions {
+				if !d.Mine {
+					g.Uncover(d.X, d.Y)
+				}
+			}
+		}
+	}
+	// Some of the frontiers should have been too big to enumerate
+	a.Greater(large, 0)
+}
+
+func TestProbabilitiesInfinite(t *testing.T) {
+	a := assert.New(t)
+
+	g, err := ms.NewInfiniteGame(40, ms.WithSeed(1))
+	a.NoError(err)
+	g.Uncover(0, 0)
+
+	appearance := g.Appearance(-20, -20, 40, 40)
+	probabilities := Probabilities(g, -20, -20, 40, 40)
+	for pos, tileType := range appearance {
+		p, ok := probabilities[pos]
+		if !isHidden(tileType) {
+			a.False(ok)
+			continue
+		}
+		a.True(ok)
+		a.True(p >= 0 && p <= 1)
+	}
+
+	// Tiles far from any number should have the game's density
+	a.InDelta(40.0/(ms.ChunkSize*ms.ChunkSize), probabilities[ms.Pos{X: -20, Y: -20}], 1e-9)
+}
+
+func TestProbabilitiesInfiniteDensity(t *testing.T) {
+	a := assert.New(t)
+
+	g, err := ms.NewInfiniteGame(20, ms.WithSeed(1),
+		ms.WithDensity(ms.DensityGradient{Step: 20}))
+	a.NoError(err)
+	g.Uncover(0, 0)
+
+	// Tiles far from any number should have the density of their chunk
+	probabilities := Probabilities(g, -ms.ChunkSize, 0, 3*ms.ChunkSize, 1)
+	a.InDelta(40.0/(ms.ChunkSize*ms.ChunkSize),
+		probabilities[ms.Pos{X: -ms.ChunkSize, Y: 0}], 1e-9)
+	a.InDelta(40.0/(ms.ChunkSize*ms.ChunkSize),
+		probabilities[ms.Pos{X: 2*ms.ChunkSize - 1, Y: 0}], 1e-9)
+
+	// Including the ones next to numbers, which can't be in a denser chunk
+	// than the prior allows
+	for pos, p := range probabilities {
+		a.True(p >= 0 && p <= 1, "pos %v", pos)
+	}
+}
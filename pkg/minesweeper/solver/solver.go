@@ -0,0 +1,221 @@
+// Package solver implements a logical minesweeper solver, that deduces which
+// tiles are certainly safe or certainly mines using only what the player can
+// see
+package solver
+
+import (
+	ms "github.com/bhollier/minesweeper/pkg/minesweeper"
+	"github.com/bhollier/minesweeper/pkg/minesweeper/internal/logic"
+	"math"
+)
+
+// Reason is the rule a deduction was made with
+type Reason = logic.Reason
+
+const (
+	// ReasonSinglePoint is for deductions from a single number, which either
+	// already has all of its mines or needs every hidden neighbour to be a mine
+	ReasonSinglePoint = logic.ReasonSinglePoint
+
+	// ReasonSubset is for deductions from two numbers, where the hidden
+	// neighbours of one are a subset of the other's
+	ReasonSubset = logic.ReasonSubset
+
+	// ReasonEnumeration is for deductions from trying every arrangement of
+	// mines around a group of numbers (and the number of remaining mines, if
+	// the whole game is being solved)
+	ReasonEnumeration = logic.ReasonEnumeration
+)
+
+// Deduction is a tile that is certainly safe or certainly a mine
+type Deduction struct {
+	ms.Pos
+
+	// Whether the tile is a mine (otherwise it's safe)
+	Mine bool
+
+	// The rule used to make the deduction
+	Reason Reason
+
+	// The positions of the numbers the deduction was made from
+	From []ms.Pos
+}
+
+// Solver deduces which hidden tiles in an area of a game are safe or mines
+type Solver struct {
+	game       ms.Game
+	x, y, w, h int
+
+	// The tiles the solver has deduced are mines. The player's flags aren't
+	// trusted, so these are remembered between steps
+	mines map[ms.Pos]bool
+}
+
+// New creates a solver for the given area of the game. For a FiniteGame, the
+// number of remaining mines is only used if the area covers the whole game
+func New(g ms.Game, x, y, w, h int) *Solver {
+	return &Solver{
+		game:  g,
+		x:     x,
+		y:     y,
+		w:     w,
+		h:     h,
+		mines: make(map[ms.Pos]bool),
+	}
+}
+
+// Step makes every deduction it can about the game's current appearance
+// using the simplest rule that finds anything, and returns them sorted by
+// position. Returns nil if nothing can be deduced without guessing
+func (s *Solver) Step() []Deduction {
+	var deductions []Deduction
+	for _, d := range s.board().Step() {
+		deduction := Deduction{
+			Pos:    ms.Pos(d.Pos),
+			Mine:   d.Mine,
+			Reason: d.Reason,
+			From:   toMsPositions(d.From),
+		}
+		// Remember the mines
+		if deduction.Mine {
+			s.mines[deduction.Pos] = true
+		}
+		deductions = append(deductions, deduction)
+	}
+	return deductions
+}
+
+// toMsPositions converts positions from the logic package
+func toMsPositions(positions []logic.Pos) []ms.Pos {
+	if positions == nil {
+		return nil
+	}
+	converted := make([]ms.Pos, len(positions))
+	for i, pos := range positions {
+		converted[i] = ms.Pos(pos)
+	}
+	return converted
+}
+
+// neighbours returns the positions next to the given position. Games that
+// aren't square grids (such as ms.HexGame) give their own neighbours
+func neighbours(g ms.Game, p ms.Pos) []ms.Pos {
+	if topology, ok := g.(interface{ Neighbours(x, y int) []ms.Pos }); ok {
+		return topology.Neighbours(p.X, p.Y)
+	}
+	positions := make([]ms.Pos, 0, 8)
+	for y := p.Y - 1; y <= p.Y+1; y++ {
+		for x := p.X - 1; x <= p.X+1; x++ {
+			if x != p.X || y != p.Y {
+				positions = append(positions, ms.Pos{X: x, Y: y})
+			}
+		}
+	}
+	return positions
+}
+
+// appearanceOf returns the appearance of the given tile from the given
+// appearance, or from the game if the tile is next to the area because the
+// game's edges wrap around
+func (s *Solver) appearanceOf(appearance map[ms.Pos]ms.TileType,
+	p ms.Pos) (ms.TileType, bool) {
+	if t, ok := appearance[p]; ok {
+		return t, true
+	}
+	// Only the game's own neighbours are always on the field
+	if _, ok := s.game.(interface{ Neighbours(x, y int) []ms.Pos }); !ok {
+		return 0, false
+	}
+	t, ok := s.game.Appearance(p.X, p.Y, 1, 1)[p]
+	return t, ok
+}
+
+func isNumber(t ms.TileType) bool {
+	_, ok := t.Number()
+	return ok
+}
+
+func isHidden(t ms.TileType) bool {
+	return t == ms.TileTypeHidden || t == ms.TileTypeFlag
+}
+
+// isMine returns true if the tile is a revealed mine, which is exploded if it
+// was uncovered by a move
+func isMine(t ms.TileType) bool {
+	return t == ms.TileTypeMine || t == ms.TileTypeExploded
+}
+
+func (s *Solver) inArea(p ms.Pos) bool {
+	return p.X >= s.x && p.X < s.x+s.w && p.Y >= s.y && p.Y < s.y+s.h
+}
+
+// coversGame returns whether the solver's area covers the whole game
+func (s *Solver) coversGame() bool {
+	sized, ok := s.game.(interface{ Size() (w, h int) })
+	if !ok {
+		return false
+	}
+	w, h := sized.Size()
+	return s.x <= 0 && s.y <= 0 && s.x+s.w >= w && s.y+s.h >= h
+}
+
+func (s *Solver) board() *logic.Board {
+	// Get the appearance with a border, so every number in the area has all
+	// of its neighbours
+	appearance := s.game.Appearance(s.x-1, s.y-1, s.w+2, s.h+2)
+
+	b := &logic.Board{
+		Unknown:        make(map[logic.Pos]bool),
+		RemainingMines: -1,
+	}
+	flags, revealedMines, remembered := 0, 0, 0
+	for pos, tileType := range appearance {
+		if !s.inArea(pos) {
+			continue
+		}
+		switch {
+		case tileType == ms.TileTypeExploded:
+			// The game has already taken exploded mines out of its remaining
+			// mines
+		case tileType == ms.TileTypeMine:
+			revealedMines++
+		case tileType == ms.TileTypeFlag:
+			flags++
+			fallthrough
+		case tileType == ms.TileTypeHidden:
+			if s.mines[pos] {
+				remembered++
+			} else {
+				b.Unknown[logic.Pos(pos)] = true
+			}
+		case isNumber(tileType):
+			mines, _ := tileType.Number()
+			c := logic.Constraint{
+				From:  logic.Pos(pos),
+				Mines: mines,
+			}
+			for _, neighbour := range neighbours(s.game, pos) {
+				neighbourType, ok := s.appearanceOf(appearance, neighbour)
+				if !ok {
+					continue
+				}
+				if isMine(neighbourType) || s.mines[neighbour] {
+					c.Mines--
+				} else if isHidden(neighbourType) {
+					c.Tiles = append(c.Tiles, logic.Pos(neighbour))
+				}
+			}
+			if len(c.Tiles) > 0 {
+				b.Constraints = append(b.Constraints, c)
+			}
+		}
+	}
+
+	// The number of remaining mines is only useful if every tile is visible
+	remaining := s.game.RemainingMines()
+	if !math.IsInf(remaining, 0) && s.coversGame() {
+		// Flags aren't trusted, so add them back on
+		b.RemainingMines = int(remaining) + flags - revealedMines - remembered
+	}
+	return b
+}
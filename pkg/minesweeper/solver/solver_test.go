@@ -0,0 +1,213 @@
+package solver
+
+import (
+	ms "github.com/bhollier/minesweeper/pkg/minesweeper"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// fakeGame is a finite game with a fixed appearance
+type fakeGame struct {
+	ms.Game
+	w, h           int
+	appearance     map[ms.Pos]ms.TileType
+	remainingMines float64
+}
+
+func newFakeGame(remainingMines float64, rows ...[]ms.TileType) *fakeGame {
+	g := &fakeGame{
+		w:              len(rows[0]),
+		h:              len(rows),
+		appearance:     make(map[ms.Pos]ms.TileType),
+		remainingMines: remainingMines,
+	}
+	for y, row := range rows {
+		for x, tileType := range row {
+			g.appearance[ms.Pos{X: x, Y: y}] = tileType
+		}
+	}
+	return g
+}
+
+func (g *fakeGame) Size() (w, h int) {
+	return g.w, g.h
+}
+
+func (g *fakeGame) RemainingMines() float64 {
+	return g.remainingMines
+}
+
+func (g *fakeGame) Appearance(x, y, w, h int) map[ms.Pos]ms.TileType {
+	appearance := make(map[ms.Pos]ms.TileType)
+	for pos, tileType := range g.appearance {
+		if pos.X >= x && pos.X < x+w && pos.Y >= y && pos.Y < y+h {
+			appearance[pos] = tileType
+		}
+	}
+	return appearance
+}
+
+const (
+	H = ms.TileTypeHidden
+	F = ms.TileTypeFlag
+)
+
+func TestSolverSubset(t *testing.T) {
+	a := assert.New(t)
+
+	g := newFakeGame(2,
+		[]ms.TileType{H, H, H},
+		[]ms.TileType{ms.TileType1, ms.TileType2, ms.TileType1})
+	s := New(g, 0, 0, g.w, g.h)
+
+	a.Equal([]Deduction{
+		{Pos: ms.Pos{X: 0, Y: 0}, Mine: true, Reason: ReasonSubset,
+			From: []ms.Pos{{X: 2, Y: 1}, {X: 1, Y: 1}}},
+		{Pos: ms.Pos{X: 2, Y: 0}, Mine: true, Reason: ReasonSubset,
+			From: []ms.Pos{{X: 0, Y: 1}, {X: 1, Y: 1}}},
+	}, s.Step())
+
+	// Knowing the mines, the middle tile is safe
+	a.Equal([]Deduction{
+		{Pos: ms.Pos{X: 1, Y: 0}, Mine: false, Reason: ReasonSinglePoint,
+			From: []ms.Pos{{X: 0, Y: 1}}},
+	}, s.Step())
+}
+
+func TestSolverIgnoresFlags(t *testing.T) {
+	a := assert.New(t)
+
+	// The flag is wrong, but the solver doesn't trust it
+	g := newFakeGame(0,
+		[]ms.TileType{F, H},
+		[]ms.TileType{ms.TileType1, ms.TileType1})
+	s := New(g, 0, 0, g.w, g.h)
+	a.Nil(s.Step())
+}
+
+func TestSolverRemainingMines(t *testing.T) {
+	a := assert.New(t)
+
+	g := newFakeGame(0,
+		[]ms.TileType{H, H},
+		[]ms.TileType{H, H})
+	deductions := New(g, 0, 0, g.w, g.h).Step()
+	a.Len(deductions, 4)
+	for _, d := range deductions {
+		a.False(d.Mine)
+		a.Equal(ReasonEnumeration, d.Reason)
+	}
+
+	g.remainingMines = 4
+	deductions = New(g, 0, 0, g.w, g.h).Step()
+	a.Len(deductions, 4)
+	for _, d := range deductions {
+		a.True(d.Mine)
+	}
+
+	// The remaining mines can't be used if the whole game isn't visible
+	a.Nil(New(g, 0, 0, 1, 2).Step())
+}
+
+func TestSolverNoGuessGame(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		g, err := ms.NewGame(16, 16, 40, ms.WithSeed(seed), ms.WithNoGuess(0, 0))
+		a.NoError(err)
+		g.Uncover(8, 8)
+
+		s := New(g, 0, 0, 16, 16)
+		var mines []ms.Pos
+		for g.State() == ms.GameStatePlaying {
+			deductions := s.Step()
+			if !a.NotEmpty(deductions) {
+				break
+			}
+			for _, d := range deductions {
+				if d.Mine {
+					mines = append(mines, d.Pos)
+				} else {
+					g.Uncover(d.X, d.Y)
+				}
+			}
+		}
+		a.Equal(ms.GameStateWin, g.State())
+
+		// Every tile deduced as a mine should still be hidden
+		appearance := g.Appearance(0, 0, 16, 16)
+		for _, pos := range mines {
+			a.Equal(ms.TileTypeHidden, appearance[pos])
+		}
+	}
+}
+
+func TestSolverExplodedMines(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		g, err := ms.NewGame(16, 16, 40, ms.WithSeed(seed), ms.WithNoGuess(0, 0),
+			ms.WithLives(3))
+		a.NoError(err)
+		g.Uncover(8, 8)
+
+		// Uncover the first mine the solver finds
+		s := New(g, 0, 0, 16, 16)
+		exploded := false
+		for g.State() == ms.GameStatePlaying {
+			deductions := s.Step()
+			if !a.NotEmpty(deductions) {
+				break
+			}
+			for _, d := range deductions {
+				if d.Mine && !exploded {
+					g.Uncover(d.X, d.Y)
+					a.Equal(2, g.Lives())
+					exploded = true
+				} else if !d.Mine {
+					g.Uncover(d.X, d.Y)
+				}
+			}
+
+			// The probabilities add up to the remaining mines, which don't
+			// include the exploded one
+			total := 0.0
+			for _, p := range Probabilities(g, 0, 0, 16, 16) {
+				total += p
+			}
+			a.InDelta(g.RemainingMines(), total, 1e-6, "seed %d", seed)
+		}
+		// The solver doesn't uncover any other mines
+		a.True(exploded)
+		a.Equal(ms.GameStateWin, g.State())
+		a.Equal(2, g.Lives())
+	}
+}
+
+func TestSolverToroidal(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 10; seed++ {
+		g, err := ms.NewGame(16, 16, 40, ms.WithSeed(seed), ms.WithToroidal(),
+			ms.WithNoGuess(0, 0))
+		a.NoError(err)
+		g.Uncover(8, 8)
+
+		// Solve the left half, where the numbers on the left edge are next to
+		// the tiles on the right edge
+		s := New(g, 0, 0, 8, 16)
+		for g.State() == ms.GameStatePlaying {
+			deductions := s.Step()
+			if len(deductions) == 0 {
+				break
+			}
+			for _, d := range deductions {
+				if !d.Mine {
+					g.Uncover(d.X, d.Y)
+				}
+			}
+		}
+		// The deductions should never be wrong
+		a.NotEqual(ms.GameStateLoss, g.State())
+	}
+}
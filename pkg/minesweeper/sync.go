@@ -0,0 +1,192 @@
+package minesweeper
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// SyncGame wraps a Game so it can be shared between goroutines. Moves are
+// made one at a time, while the game's state can be read concurrently
+type SyncGame struct {
+	mu   sync.RWMutex
+	game Game
+
+	// Ends the game when its time limit runs out, see WithTimeLimit
+	timer *time.Timer
+}
+
+// NewSyncGame wraps the given game. The game shouldn't be used directly
+// afterwards
+func NewSyncGame(g Game) *SyncGame {
+	s := &SyncGame{game: g}
+	s.watchTimeLimit()
+	return s
+}
+
+// timeLimited is a game whose time limit can run out between moves, see
+// WithTimeLimit
+type timeLimited interface {
+	timeLeft(now time.Time) (time.Duration, bool)
+	endIfTimeUp(now time.Time)
+}
+
+// watchTimeLimit starts a timer to end the game when its time limit runs
+// out, so its StateChangedEvent is emitted without waiting for the next move.
+// The game needs to be locked, unless no other goroutine has it yet
+func (g *SyncGame) watchTimeLimit() {
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	limited, ok := g.game.(timeLimited)
+	if !ok {
+		return
+	}
+	left, ok := limited.timeLeft(time.Now())
+	if !ok {
+		return
+	}
+	g.timer = time.AfterFunc(left, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		limited.endIfTimeUp(time.Now())
+	})
+}
+
+// unlock unlocks the game after it's been changed. The change might have
+// started the game (or reset it), so the time limit is watched again first
+func (g *SyncGame) unlock() {
+	g.watchTimeLimit()
+	g.mu.Unlock()
+}
+
+// Do calls f with the wrapped game while no other goroutine is using it, to
+// use methods specific to the game's type
+func (g *SyncGame) Do(f func(Game)) {
+	g.mu.Lock()
+	defer g.unlock()
+	f(g.game)
+}
+
+func (g *SyncGame) Reset(numMines int) error {
+	g.mu.Lock()
+	defer g.unlock()
+	return g.game.Reset(numMines)
+}
+
+func (g *SyncGame) Uncover(x, y int) GameState {
+	g.mu.Lock()
+	defer g.unlock()
+	return g.game.Uncover(x, y)
+}
+
+func (g *SyncGame) Chord(x, y int) GameState {
+	g.mu.Lock()
+	defer g.unlock()
+	return g.game.Chord(x, y)
+}
+
+func (g *SyncGame) Flag(x, y int) float64 {
+	g.mu.Lock()
+	defer g.unlock()
+	return g.game.Flag(x, y)
+}
+
+func (g *SyncGame) Undo() bool {
+	g.mu.Lock()
+	defer g.unlock()
+	return g.game.Undo()
+}
+
+func (g *SyncGame) Redo() bool {
+	g.mu.Lock()
+	defer g.unlock()
+	return g.game.Redo()
+}
+
+func (g *SyncGame) RemainingMines() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.game.RemainingMines()
+}
+
+func (g *SyncGame) State() GameState {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.game.State()
+}
+
+func (g *SyncGame) FirstMovePolicy() (FirstMovePolicy, int) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.game.FirstMovePolicy()
+}
+
+func (g *SyncGame) Lives() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.game.Lives()
+}
+
+func (g *SyncGame) StartTime() time.Time {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.game.StartTime()
+}
+
+func (g *SyncGame) SinceStart() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.game.SinceStart()
+}
+
+func (g *SyncGame) Appearance(x, y, w, h int) map[Pos]TileType {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.game.Appearance(x, y, w, h)
+}
+
+// Subscribe calls f with the game's events, see Game.Subscribe. f is called
+// while the game is locked for the move, so it can't use the game (or
+// unsubscribe) itself
+func (g *SyncGame) Subscribe(f func(Event)) (unsubscribe func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	unsubscribeGame := g.game.Subscribe(f)
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		unsubscribeGame()
+	}
+}
+
+// Clone returns a copy of the game, also wrapped in a SyncGame
+func (g *SyncGame) Clone() Game {
+	return NewSyncGame(g.Snapshot())
+}
+
+func (g *SyncGame) Replay() *Replay {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.game.Replay()
+}
+
+// Save the game into the given io.Writer. The game is only locked while it's
+// copied (see Snapshot), so moves can be made while the copy is saved
+func (g *SyncGame) Save(w io.Writer) error {
+	return g.Snapshot().Save(w)
+}
+
+// Snapshot returns an independent copy of the wrapped game as it is now, to
+// be used without blocking moves. Moves are blocked while the game is cloned
+// though, which copies the tiles in memory (the whole field of a FiniteGame,
+// or an InfiniteGame's chunks that aren't in its chunk store), the undo
+// history and the replay, so it takes longer the bigger the game and the more
+// moves have been made. See BenchmarkSyncSnapshotFinite and
+// BenchmarkSyncSnapshotInfinite
+func (g *SyncGame) Snapshot() Game {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.game.Clone()
+}
@@ -0,0 +1,130 @@
+package minesweeper
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestSyncGame(t *testing.T) {
+	a := assert.New(t)
+
+	finite, err := NewGame(32, 32, 100, WithSeed(1))
+	a.NoError(err)
+	infinite, err := NewInfiniteGame(40, WithSeed(1))
+	a.NoError(err)
+
+	for _, game := range []Game{finite, infinite} {
+		g := NewSyncGame(game)
+
+		// Subscribers are called with the game locked, so they don't need
+		// their own locking
+		events := 0
+		unsubscribe := g.Subscribe(func(Event) { events++ })
+
+		// Make moves and read the game from lots of goroutines at once
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 32; j++ {
+					g.Uncover(i*4, j)
+					g.Flag(j, i*4)
+					g.Chord(i, j)
+					g.Appearance(0, 0, 32, 32)
+					g.State()
+					g.SinceStart()
+					g.RemainingMines()
+					a.NoError(g.Save(new(bytes.Buffer)))
+					if j%8 == 0 {
+						g.Undo()
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+		a.NotZero(events)
+		unsubscribe()
+
+		// The snapshot should match the game, but be independent of it
+		snapshot := g.Snapshot()
+		a.Equal(g.Appearance(0, 0, 32, 32), snapshot.Appearance(0, 0, 32, 32))
+		state := g.State()
+		a.NoError(snapshot.Reset(40))
+		a.Equal(GameStateStart, snapshot.State())
+		a.Equal(state, g.State())
+
+		// The game isn't locked while it's written, so moves can be made
+		before := g.Appearance(0, 0, 32, 32)
+		var buf bytes.Buffer
+		a.NoError(g.Save(writerFunc(func(b []byte) (int, error) {
+			g.Flag(31, 31)
+			return buf.Write(b)
+		})))
+		loaded, err := Load(&buf)
+		a.NoError(err)
+		a.Equal(before, loaded.Appearance(0, 0, 32, 32))
+	}
+}
+
+// writerFunc is an io.Writer that calls the function
+type writerFunc func(b []byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
+
+// playMoves makes n moves spread over the given area, so the game has a long
+// history and replay
+func playMoves(game Game, n, w, h int) {
+	for i := 0; i < n; i++ {
+		x, y := (i*7919)%w, (i*104729)%h
+		if i%3 == 0 {
+			game.Flag(x, y)
+		} else {
+			game.Uncover(x, y)
+		}
+	}
+}
+
+func BenchmarkSyncSnapshotFinite(b *testing.B) {
+	for _, size := range []int{16, 256, 1024} {
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			game, err := NewGame(size, size, size*size/10, WithSeed(1),
+				WithLives(UnlimitedLives))
+			if err != nil {
+				b.Fatal(err)
+			}
+			g := NewSyncGame(game)
+			playMoves(g, 1000, size, size)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				g.Snapshot()
+			}
+		})
+	}
+}
+
+func BenchmarkSyncSnapshotInfinite(b *testing.B) {
+	for _, moves := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("%d moves", moves), func(b *testing.B) {
+			game, err := NewInfiniteGame(40, WithSeed(1),
+				WithLives(UnlimitedLives),
+				WithChunkCache(64, newMemoryChunkStore()))
+			if err != nil {
+				b.Fatal(err)
+			}
+			g := NewSyncGame(game)
+			playMoves(g, moves, 100*ChunkSize, 100*ChunkSize)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				g.Snapshot()
+			}
+		})
+	}
+}
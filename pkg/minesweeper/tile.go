@@ -1,5 +1,7 @@
 package minesweeper
 
+import "math"
+
 type TileType int
 
 const (
@@ -20,6 +22,35 @@ const (
 	NumTileTypes
 )
 
+// TileTypeExploded is only used for conveying the appearance of a tile, it's
+// a mine that was uncovered by a move (see WithLives). A tile's type is saved
+// in a byte, so it comes after every number a tile can have, instead of
+// moving the numbers above 8
+const TileTypeExploded = TileType(math.MaxUint8 + 1)
+
+// TileTypeNumber returns the type of a tile with n neighbouring mines. Tiles
+// can have more than 8 neighbours with some neighbourhoods (see
+// Neighbourhood), so the numbers above 8 come after NumTileTypes
+func TileTypeNumber(n int) TileType {
+	if n <= 8 {
+		return TileType(int(TileTypeEmpty) + n)
+	}
+	return TileType(int(NumTileTypes) + n - 9)
+}
+
+// Number returns the number of neighbouring mines the tile type shows, or
+// false if the type isn't a number
+func (t TileType) Number() (int, bool) {
+	switch {
+	case t >= TileTypeEmpty && t <= TileType8:
+		return int(t - TileTypeEmpty), true
+	case t >= NumTileTypes && t < TileTypeExploded:
+		return int(t-NumTileTypes) + 9, true
+	default:
+		return 0, false
+	}
+}
+
 // Tile represents a single minesweeper tile
 type Tile struct {
 	// Whether the tile has been discovered
@@ -28,28 +59,46 @@ type Tile struct {
 	// Whether there is a flag on the tile
 	Flagged bool
 
+	// Whether the tile is a mine that was uncovered by a move
+	Exploded bool
+
 	// The tile's type
 	Type TileType
+
+	// The number of mines on the tile if it's a mine, and the number of flags
+	// on the tile if it's flagged. These are only more than 1 in multimine
+	// games, see WithMultiMines
+	Mines, Flags uint8
 }
 
-func (t Tile) toByte() (b byte) {
+// tileSize is the number of bytes in a serialised tile
+const tileSize = 4
+
+func (t Tile) toBytes() (b [tileSize]byte) {
 	if t.Discovered {
-		b |= 1 << 0
+		b[0] |= 1 << 0
 	}
 	if t.Flagged {
-		b |= 1 << 1
+		b[0] |= 1 << 1
+	}
+	if t.Exploded {
+		b[0] |= 1 << 2
 	}
-	b |= byte(t.Type) << 4
+	// The type has its own byte, so there's room for the bigger numbers
+	b[1] = byte(t.Type)
+	b[2], b[3] = t.Mines, t.Flags
 	return
 }
 
-func tileFromByte(b byte) Tile {
+func tileFromBytes(b [tileSize]byte) Tile {
 	return Tile{
-		// First two bits are the booleans
-		Discovered: b&1 != 0,
-		Flagged:    b&2 != 0,
-		// Last 4 bits are the type
-		Type: TileType(b >> 4),
+		// First three bits are the booleans
+		Discovered: b[0]&1 != 0,
+		Flagged:    b[0]&2 != 0,
+		Exploded:   b[0]&4 != 0,
+		Type:       TileType(b[1]),
+		Mines:      b[2],
+		Flags:      b[3],
 	}
 }
 
@@ -60,10 +109,11 @@ func (f Field) toBytes() (b []byte) {
 		return
 	}
 	h, w := len(f), len(f[0])
-	b = make([]byte, w*h)
+	b = make([]byte, w*h*tileSize)
 	for y, row := range f {
 		for x, tile := range row {
-			b[(y*w)+x] = tile.toByte()
+			tileBytes := tile.toBytes()
+			copy(b[((y*w)+x)*tileSize:], tileBytes[:])
 		}
 	}
 	return
@@ -74,7 +124,9 @@ func fieldFromBytes(w, h int, b []byte) (f Field) {
 	for y := 0; y < h; y++ {
 		f[y] = make([]Tile, w)
 		for x := 0; x < w; x++ {
-			f[y][x] = tileFromByte(b[(y*w)+x])
+			var tileBytes [tileSize]byte
+			copy(tileBytes[:], b[((y*w)+x)*tileSize:])
+			f[y][x] = tileFromBytes(tileBytes)
 		}
 	}
 	return
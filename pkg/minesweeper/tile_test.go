@@ -2,11 +2,12 @@ package minesweeper
 
 import (
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 )
 
 func tileSerialiseRoundtrip(a *assert.Assertions, t Tile) {
-	a.Equal(t, tileFromByte(t.toByte()))
+	a.Equal(t, tileFromBytes(t.toBytes()))
 }
 
 func TestTileSerialiseRoundtrip(t *testing.T) {
@@ -37,4 +38,39 @@ func TestTileSerialiseRoundtrip(t *testing.T) {
 		Flagged:    false,
 		Type:       TileType3,
 	})
+	tileSerialiseRoundtrip(a, Tile{
+		Discovered: true,
+		Flagged:    false,
+		Type:       TileTypeNumber(24),
+	})
+	tileSerialiseRoundtrip(a, Tile{
+		Discovered: false,
+		Flagged:    true,
+		Exploded:   true,
+		Type:       TileTypeMine,
+		Mines:      3,
+		Flags:      2,
+	})
+}
+
+func TestTileTypeNumber(t *testing.T) {
+	a := assert.New(t)
+
+	for n := 0; n <= 24; n++ {
+		number, ok := TileTypeNumber(n).Number()
+		a.True(ok)
+		a.Equal(n, number)
+	}
+	a.Equal(TileType3, TileTypeNumber(3))
+
+	// The numbers above 8 are after the other types, which can't move them
+	a.Equal(TileType(12), TileTypeNumber(9))
+	a.Equal(TileType(math.MaxUint8), TileTypeNumber(math.MaxUint8-int(NumTileTypes)+9))
+
+	// The other types aren't numbers
+	for _, tileType := range []TileType{
+		TileTypeMine, TileTypeHidden, TileTypeFlag, TileTypeExploded} {
+		_, ok := tileType.Number()
+		a.False(ok)
+	}
 }
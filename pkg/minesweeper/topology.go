@@ -0,0 +1,174 @@
+package minesweeper
+
+// tiling is the shape of the tiles in a finite game
+type tiling uint8
+
+const (
+	// tilingSquare is the usual grid of squares, where each tile has the 8
+	// tiles around it as neighbours
+	tilingSquare = tiling(iota)
+
+	// tilingHex is a grid of hexagons, where each tile has 6 neighbours. The
+	// tiles are stored in rows, and the odd rows are shifted half a tile to
+	// the right
+	tilingHex
+
+	// tilingCube is a grid of cubes, where each tile has the 26 tiles around
+	// it as neighbours. The layers of the grid are stored one after another,
+	// so tile (x, y, z) is at (x, y + z*layerHeight)
+	tilingCube
+)
+
+// The offsets of the neighbours in a hex tiling, which depend on the row
+var (
+	hexEvenRowOffsets = []Pos{
+		{-1, -1}, {0, -1},
+		{-1, 0}, {1, 0},
+		{-1, 1}, {0, 1},
+	}
+	hexOddRowOffsets = []Pos{
+		{0, -1}, {1, -1},
+		{-1, 0}, {1, 0},
+		{0, 1}, {1, 1},
+	}
+)
+
+// offsets returns the offsets of the neighbours of the given position
+func (t tiling) offsets(p Pos) []Pos {
+	switch t {
+	case tilingHex:
+		if mod(p.Y, 2) == 1 {
+			return hexOddRowOffsets
+		}
+		return hexEvenRowOffsets
+	default:
+		return NeighbourhoodMoore
+	}
+}
+
+// topology decides which tiles in a finite game are next to each other
+type topology struct {
+	tiling tiling
+
+	// Whether the edges of the field wrap around, so the tiles on opposite
+	// edges are next to each other
+	wrap bool
+
+	// The neighbourhood of a square tiling, or nil for the tiling's usual
+	// neighbours
+	neighbourhood Neighbourhood
+
+	// The number of layers in the field, which is 1 unless it's a cube tiling
+	depth int
+}
+
+// offsets returns the offsets of the neighbours of the given position
+func (t topology) offsets(p Pos) []Pos {
+	if t.neighbourhood != nil {
+		return t.neighbourhood
+	}
+	return t.tiling.offsets(p)
+}
+
+// maxNeighbours returns the most neighbours a tile can have
+func (t topology) maxNeighbours() int {
+	if t.tiling == tilingCube {
+		return 26
+	}
+	return len(t.offsets(Pos{}))
+}
+
+// neighbours returns the positions next to the given position, that are
+// inside a field of the given size
+func (t topology) neighbours(p Pos, w, h int) []Pos {
+	if t.tiling == tilingCube {
+		return t.cubeNeighbours(p, w, h)
+	}
+
+	offsets := t.offsets(p)
+	neighbours := make([]Pos, 0, len(offsets))
+	for _, offset := range offsets {
+		neighbour := Pos{p.X + offset.X, p.Y + offset.Y}
+		if t.wrap {
+			neighbour = Pos{mod(neighbour.X, w), mod(neighbour.Y, h)}
+			// On small fields the same tile can be reached from more than
+			// one side
+			if neighbour == p || containsPos(neighbours, neighbour) {
+				continue
+			}
+		} else if neighbour.X < 0 || neighbour.Y < 0 ||
+			neighbour.X >= w || neighbour.Y >= h {
+			// Skip the tile if it's not on the grid
+			continue
+		}
+		neighbours = append(neighbours, neighbour)
+	}
+	return neighbours
+}
+
+// cubeNeighbours returns the positions of the 26 tiles around the given
+// position in a cube tiling, in a field of the given size
+func (t topology) cubeNeighbours(p Pos, w, h int) []Pos {
+	layerHeight := h / t.depth
+	x, y, z := p.X, mod(p.Y, layerHeight), p.Y/layerHeight
+	neighbours := make([]Pos, 0, 26)
+	for neighbourZ := z - 1; neighbourZ <= z+1; neighbourZ++ {
+		for neighbourY := y - 1; neighbourY <= y+1; neighbourY++ {
+			for neighbourX := x - 1; neighbourX <= x+1; neighbourX++ {
+				nx, ny, nz := neighbourX, neighbourY, neighbourZ
+				if t.wrap {
+					nx, ny, nz = mod(nx, w), mod(ny, layerHeight), mod(nz, t.depth)
+				} else if nx < 0 || ny < 0 || nz < 0 ||
+					nx >= w || ny >= layerHeight || nz >= t.depth {
+					// Skip the tile if it's not on the grid
+					continue
+				}
+				neighbour := Pos{nx, ny + nz*layerHeight}
+				// Skip the center tile, and tiles that can be reached from more
+				// than one side on small fields
+				if neighbour == p || containsPos(neighbours, neighbour) {
+					continue
+				}
+				neighbours = append(neighbours, neighbour)
+			}
+		}
+	}
+	return neighbours
+}
+
+func containsPos(positions []Pos, p Pos) bool {
+	for _, pos := range positions {
+		if pos == p {
+			return true
+		}
+	}
+	return false
+}
+
+// header returns the save header of a finite game with the topology
+func (t topology) header() saveHeader {
+	h := newHeader(saveHeaderFiniteGameType)
+	switch t.tiling {
+	case tilingHex:
+		h.GameType = saveHeaderHexGameType
+	case tilingCube:
+		h.GameType = saveHeaderGame3DType
+	}
+	if t.wrap {
+		h.Flags |= saveHeaderWrapFlag
+	}
+	return h
+}
+
+// topologyFromHeader returns the topology of a finite game with the given
+// save header
+func topologyFromHeader(h saveHeader) topology {
+	t := topology{wrap: h.Flags&saveHeaderWrapFlag != 0}
+	switch h.GameType {
+	case saveHeaderHexGameType:
+		t.tiling = tilingHex
+	case saveHeaderGame3DType:
+		t.tiling = tilingCube
+	}
+	return t
+}
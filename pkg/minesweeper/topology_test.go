@@ -0,0 +1,105 @@
+package minesweeper
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestToroidalNeighbours(t *testing.T) {
+	a := assert.New(t)
+
+	game, err := NewGame(8, 8, 10, WithToroidal())
+	a.NoError(err)
+	g := game.(*FiniteGame)
+	a.True(g.Toroidal())
+
+	// The corners are next to the opposite edges
+	a.ElementsMatch([]Pos{
+		{7, 7}, {0, 7}, {1, 7},
+		{7, 0}, {1, 0},
+		{7, 1}, {0, 1}, {1, 1},
+	}, g.Neighbours(0, 0))
+
+	// Hex fields wrap too
+	game, err = NewHexGame(8, 8, 10, WithToroidal())
+	a.NoError(err)
+	a.ElementsMatch([]Pos{{7, 7}, {0, 7}, {7, 0}, {1, 0}, {7, 1}, {0, 1}},
+		game.(*HexGame).Neighbours(0, 0))
+	a.ElementsMatch([]Pos{{7, 6}, {0, 6}, {6, 7}, {0, 7}, {7, 0}, {0, 0}},
+		game.(*HexGame).Neighbours(7, 7))
+
+	// But only with an even number of rows
+	_, err = NewHexGame(8, 7, 10, WithToroidal())
+	a.Error(err)
+
+	// Tiles shouldn't be repeated on small fields
+	game, err = NewGame(2, 5, 0, WithToroidal())
+	a.NoError(err)
+	a.Equal([]Pos{{1, 4}, {0, 4}, {1, 0}, {1, 1}, {0, 1}},
+		game.(*FiniteGame).Neighbours(0, 0))
+}
+
+func TestToroidalPopulate(t *testing.T) {
+	a := assert.New(t)
+
+	for seed := int64(0); seed < 20; seed++ {
+		game, err := NewGame(16, 16, 100, WithSeed(seed), WithToroidal())
+		a.NoError(err)
+		g := game.(*FiniteGame)
+		g.Uncover(0, 0)
+
+		// The tiles next to the first move, across the edges, are never mines
+		a.NotEqual(TileTypeMine, g.field[0][0].Type)
+		for _, pos := range g.Neighbours(0, 0) {
+			a.NotEqual(TileTypeMine, g.field[pos.Y][pos.X].Type)
+		}
+
+		// The numbers count the neighbours across the edges
+		for y, row := range g.field {
+			for x, tile := range row {
+				if tile.Type == TileTypeMine {
+					continue
+				}
+				count := 0
+				for _, pos := range g.Neighbours(x, y) {
+					if g.field[pos.Y][pos.X].Type == TileTypeMine {
+						count++
+					}
+				}
+				a.Equal(TileType(int(TileTypeEmpty)+count), tile.Type)
+			}
+		}
+
+		// The flood fill should also cross the edges
+		if g.field[0][0].Type == TileTypeEmpty {
+			for _, pos := range g.Neighbours(0, 0) {
+				a.True(g.field[pos.Y][pos.X].Discovered)
+			}
+		}
+	}
+}
+
+func TestToroidalSerialiseRoundtrip(t *testing.T) {
+	a := assert.New(t)
+
+	for _, newGame := range []func(int, int, int, ...Option) (Game, error){
+		NewGame, NewHexGame} {
+		game, err := newGame(16, 16, 40, WithSeed(1), WithToroidal())
+		a.NoError(err)
+		game.Uncover(0, 0)
+
+		var buf bytes.Buffer
+		a.NoError(game.Save(&buf))
+		loadedGame, err := Load(&buf)
+		a.NoError(err)
+		a.IsType(game, loadedGame)
+
+		// The loaded game should still wrap
+		a.Equal(game.(interface{ Toroidal() bool }).Toroidal(),
+			loadedGame.(interface{ Toroidal() bool }).Toroidal())
+		a.Equal(game.(interface{ Neighbours(x, y int) []Pos }).Neighbours(0, 0),
+			loadedGame.(interface{ Neighbours(x, y int) []Pos }).Neighbours(0, 0))
+		a.Equal(game.Appearance(0, 0, 16, 16), loadedGame.Appearance(0, 0, 16, 16))
+	}
+}
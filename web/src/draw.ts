@@ -20,7 +20,9 @@ export const SPRITES = {
         8: {x: 80, y: 0, w: TILE_SIZE, h: TILE_SIZE} as Rect,
         MINE: {x: 90, y: 0, w: TILE_SIZE, h: TILE_SIZE} as Rect,
         HIDDEN: {x: 100, y: 0, w: TILE_SIZE, h: TILE_SIZE} as Rect,
-        FLAG: {x: 110, y: 0, w: TILE_SIZE, h: TILE_SIZE} as Rect
+        FLAG: {x: 110, y: 0, w: TILE_SIZE, h: TILE_SIZE} as Rect,
+        // The spritesheet doesn't have an exploded mine yet
+        EXPLODED: {x: 90, y: 0, w: TILE_SIZE, h: TILE_SIZE} as Rect
     },
 
     MODAL: {
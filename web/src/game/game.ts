@@ -114,6 +114,14 @@ export default class Game extends State {
                             this.handleState(state);
                         });
 
+                    // Middle mouse button
+                } else if (event.button === 1) {
+                    goio.chord(event.pos)
+                        .then(async state => {
+                            await this.draw(true);
+                            this.handleState(state);
+                        });
+
                     // Right mouse button
                 } else if (event.button === 2) {
                     goio.flag(event.pos)
@@ -9,15 +9,52 @@ export enum GameState {
     Loss = 'loss',
 }
 
-export type InitRequestData = {
+export enum Topology {
+    Square = 'square',
+    Hex = 'hex',
+    // The layers of a cube game are stacked vertically in its appearance
+    Cube = 'cube',
+}
+
+// Which tiles are kept free of mines for the first move
+export enum FirstMovePolicy {
+    SafeOpening = 'safe opening',
+    Unprotected = 'unprotected',
+    SafeTile = 'safe tile',
+    // Opens at least minOpening tiles
+    MinOpening = 'min opening',
+}
+
+type FirstMoveData = {
+    firstMove?: FirstMovePolicy,
+    // The fewest tiles the first move opens, only for FirstMovePolicy.MinOpening
+    minOpening?: number
+}
+
+export type InitRequestData = FirstMoveData & ({
     width: number,
     height: number,
-    mines: number
+    mines: number,
+    noGuess?: boolean,
+    topology?: Topology,
+    toroidal?: boolean,
+    // The number of layers, only for the cube topology
+    depth?: number,
+    // The number of lives, or Infinity to never lose the game
+    lives?: number
 } | {
-    mineDensity: number
-}
-
-export function init(data: InitRequestData): Promise<void> {
+    mineDensity: number,
+    lives?: number,
+    // The game ends when the time limit (in milliseconds) runs out or the
+    // target score is reached
+    timeLimit?: number,
+    targetScore?: number
+})
+
+// The game's settings, including the defaults
+export type InitResponseData = InitRequestData
+
+export function init(data: InitRequestData): Promise<InitResponseData> {
     return postMessage('init', data);
 }
 
@@ -32,6 +69,9 @@ export function appearance(data: AppearanceRequestData): Promise<AppearanceRespo
 export type StateResponseData = {
     state: string,
     timer: number,
+    lives: number,
+    // Only for infinite games
+    score?: number,
     remainingMines: number
 }
 
@@ -43,13 +83,24 @@ export type UncoverRequestData = Pos
 
 export type UncoverResponseData = {
     state: GameState,
-    timer: number
+    timer: number,
+    lives: number,
+    // Only for infinite games
+    score?: number
 }
 
 export function uncover(data: UncoverRequestData): Promise<UncoverResponseData> {
     return postMessage('uncover', data);
 }
 
+export type ChordRequestData = Pos
+
+export type ChordResponseData = UncoverResponseData
+
+export function chord(data: ChordRequestData): Promise<ChordResponseData> {
+    return postMessage('chord', data);
+}
+
 export type FlagRequestData = Pos
 
 export type FlagResponseData = {
@@ -60,6 +111,18 @@ export function flag(data: FlagRequestData): Promise<FlagResponseData> {
     return postMessage('flag', data);
 }
 
+export type HistoryResponseData = StateResponseData & {
+    changed: boolean
+}
+
+export function undo(): Promise<HistoryResponseData> {
+    return postMessage('undo');
+}
+
+export function redo(): Promise<HistoryResponseData> {
+    return postMessage('redo');
+}
+
 export type SaveResponseData = string
 
 export function save(): Promise<SaveResponseData> {